@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/essentialshandler"
+)
+
+func getEssentials(
+	w http.ResponseWriter, r *http.Request, essentialsHandler *essentialshandler.EssentialsHandler,
+) {
+	query := r.URL.Query()
+
+	kind := query.Get("kind")
+	if kind != "" && !essentialshandler.ValidKind(kind) {
+		http.Error(w, "kind must be word, phrase or lemma", http.StatusBadRequest)
+		return
+	}
+
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	report, err := essentialsHandler.GetTopEssentials(query.Get("from"), query.Get("to"), kind, limit)
+	if err != nil {
+		http.Error(w, "Failed to fetch data", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, report)
+}
+
+func getTrends(
+	w http.ResponseWriter, r *http.Request, essentialsHandler *essentialshandler.EssentialsHandler,
+) {
+	query := r.URL.Query()
+
+	kind := query.Get("kind")
+	if kind != "" && !essentialshandler.ValidKind(kind) {
+		http.Error(w, "kind must be word, phrase or lemma", http.StatusBadRequest)
+		return
+	}
+
+	minSupport, _ := strconv.Atoi(query.Get("minSupport"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
+
+	report, err := essentialsHandler.GetTrends(query.Get("from"), query.Get("to"), kind, minSupport, limit)
+	if errors.Is(err, config.ErrInvalidDate) {
+		http.Error(w, "from and to must be dates in YYYY.MM.DD format", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to fetch data", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, report)
+}
+
+func getEntityStats(
+	w http.ResponseWriter, r *http.Request, essentialsHandler *essentialshandler.EssentialsHandler,
+) {
+	query := r.URL.Query()
+
+	report, err := essentialsHandler.GetEntityStats(query.Get("from"), query.Get("to"), query.Get("type"))
+	if err != nil {
+		http.Error(w, "Failed to fetch data", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, report)
+}
+
+func getLengthStats(
+	w http.ResponseWriter, r *http.Request, essentialsHandler *essentialshandler.EssentialsHandler,
+) {
+	query := r.URL.Query()
+
+	report, err := essentialsHandler.GetLengthStats(
+		query.Get("from"), query.Get("to"), query.Get("type"), query.Get("bySubtype") != "")
+	if err != nil {
+		http.Error(w, "Failed to fetch data", http.StatusInternalServerError)
+		return
+	}
+
+	if query.Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="inscription_lengths.csv"`)
+		if err := report.WriteCSV(w); err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	writeJSON(w, report)
+}
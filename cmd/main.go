@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -10,14 +11,19 @@ import (
 	"strconv"
 
 	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/customershandler"
 	"github.com/crush-on-anechka/ktn_stats/db"
+	"github.com/crush-on-anechka/ktn_stats/essentialshandler"
+	"github.com/crush-on-anechka/ktn_stats/issueshandler"
 	"github.com/crush-on-anechka/ktn_stats/messagesender"
+	"github.com/crush-on-anechka/ktn_stats/previewhandler"
+	"github.com/crush-on-anechka/ktn_stats/reportshandler"
 	"github.com/crush-on-anechka/ktn_stats/tasks"
 	"github.com/gorilla/mux"
 )
 
 func main() {
-	taskMode, webMode, taskFlags, year := initFlags()
+	taskMode, webMode, taskFlags, taskArgs := initFlags()
 
 	botToken := config.Envs.TelegramToken
 	chatID := int64(config.Envs.TelegramChatID)
@@ -29,13 +35,13 @@ func main() {
 	if *webMode {
 		startServer(sender)
 	} else if *taskMode {
-		runTask(taskFlags, year, sender)
+		runTask(taskFlags, taskArgs, sender)
 	} else {
 		log.Println("No mode specified. Use --task or --web")
 	}
 }
 
-func runTask(taskFlags map[string]*bool, year *string, sender *messagesender.Sender) {
+func runTask(taskFlags map[string]*bool, taskArgs map[string]*string, sender *messagesender.Sender) {
 	switch {
 	case *taskFlags["init_db"]:
 		err := tasks.InitDB()
@@ -48,29 +54,75 @@ func runTask(taskFlags map[string]*bool, year *string, sender *messagesender.Sen
 		handleSuccess(sender, "Fieldnames check: OK")
 
 	case *taskFlags["store_all"]:
-		err := tasks.StoreAllSpreadsheets()
+		report, err := tasks.StoreAllSpreadsheets()
 		handleError(err, sender, "Failed to store spreadsheets data")
 		handleSuccess(sender, "Spreadsheets data was successfully stored")
+		handleReport(sender, report)
 
 	case *taskFlags["store_latest"]:
-		err := tasks.StoreLatestSpreadsheet()
+		report, err := tasks.StoreLatestSpreadsheet()
 		handleError(err, sender, "Failed to store latest spreadsheet data")
 		handleSuccess(sender, "Latest spreadsheet data was successfully stored")
+		handleReport(sender, report)
 
 	case *taskFlags["store_by_year"]:
-		if *year == "" {
+		year := *taskArgs["year"]
+		if year == "" {
 			log.Println("You must provide year using -year")
 			os.Exit(1)
 		}
-		err := tasks.StoreSpreadsheet(*year)
+		report, err := tasks.StoreSpreadsheet(year)
 		handleError(err, sender, "Failed to store spreadsheet data")
 		handleSuccess(sender, "Spreadsheet data was successfully stored")
+		handleReport(sender, report)
 
 	case *taskFlags["update_essentials"]:
 		err := tasks.UpdateEssentials()
 		handleError(err, sender, "Failed to update essentials")
 		handleSuccess(sender, "Essential words and phrases were successfully updated")
 
+	case *taskFlags["check_issues"]:
+		report, err := tasks.CheckIssues()
+		handleError(err, sender, "Failed to check data quality")
+		handleSuccess(sender, "Data quality check was successfully completed")
+		handleReport(sender, report)
+
+	case *taskFlags["notify_unpaid"]:
+		report, err := tasks.NotifyUnpaid()
+		handleError(err, sender, "Failed to build unpaid orders report")
+		handleSuccess(sender, "Unpaid orders report was successfully built")
+		handleReport(sender, report)
+
+	case *taskFlags["resolve_customers"]:
+		err := tasks.ResolveCustomers()
+		handleError(err, sender, "Failed to resolve customers")
+		handleSuccess(sender, "Customers were successfully resolved")
+
+	case *taskFlags["merge_customers"], *taskFlags["split_customers"]:
+		keys := *taskArgs["keys"]
+		if keys == "" {
+			log.Println("You must provide two customer keys using -keys")
+			os.Exit(1)
+		}
+		kind := config.CustomerOverrideMerge
+		if *taskFlags["split_customers"] {
+			kind = config.CustomerOverrideSplit
+		}
+		err := tasks.OverrideCustomers(kind, keys)
+		handleError(err, sender, "Failed to override customers")
+		handleSuccess(sender, "Customers override was successfully applied")
+
+	case *taskFlags["build_orders"]:
+		err := tasks.BuildOrders()
+		handleError(err, sender, "Failed to build orders")
+		handleSuccess(sender, "Orders were successfully built")
+
+	case *taskFlags["check_essentials_rules"]:
+		report, err := tasks.CheckEssentialsRules()
+		handleError(err, sender, "Failed to check essentials rules")
+		handleSuccess(sender, "Essentials rules check was successfully completed")
+		handleReport(sender, report)
+
 	default:
 		fmt.Println("No task specified. Available flags:")
 		flag.PrintDefaults()
@@ -93,6 +145,81 @@ func startServer(sender *messagesender.Sender) {
 		fetchDataFromDB(w, r, db)
 	})
 
+	essentialsHandler := essentialshandler.New(db)
+
+	r.HandleFunc("/inscriptions/check", func(w http.ResponseWriter, r *http.Request) {
+		checkInscription(w, r, essentialsHandler)
+	})
+
+	r.HandleFunc("/essentials", func(w http.ResponseWriter, r *http.Request) {
+		getEssentials(w, r, essentialsHandler)
+	})
+
+	r.HandleFunc("/essentials/trends", func(w http.ResponseWriter, r *http.Request) {
+		getTrends(w, r, essentialsHandler)
+	})
+
+	r.HandleFunc("/essentials/entities", func(w http.ResponseWriter, r *http.Request) {
+		getEntityStats(w, r, essentialsHandler)
+	})
+
+	r.HandleFunc("/essentials/lengths", func(w http.ResponseWriter, r *http.Request) {
+		getLengthStats(w, r, essentialsHandler)
+	})
+
+	customersHandler := customershandler.New(db)
+
+	r.HandleFunc("/customers/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		getCustomerProfile(w, r, customersHandler)
+	})
+
+	r.HandleFunc("/customer/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./static/customer.html")
+	})
+
+	previewHandler := previewhandler.New(db)
+
+	r.HandleFunc("/orders/{date:[0-9]{4}\\.[0-9]{2}\\.[0-9]{2}}/{row:[0-9]+}/preview.svg",
+		func(w http.ResponseWriter, r *http.Request) {
+			getInscriptionPreview(w, r, previewHandler)
+		})
+
+	r.HandleFunc("/admin/issues", func(w http.ResponseWriter, r *http.Request) {
+		getIssues(w, r, db)
+	})
+
+	issuesHandler := issueshandler.New(db)
+
+	r.HandleFunc("/admin/engraving", func(w http.ResponseWriter, r *http.Request) {
+		getEngravingViolations(w, r, issuesHandler)
+	})
+
+	r.HandleFunc("/admin/ingestion", func(w http.ResponseWriter, r *http.Request) {
+		getIngestionStats(w, r, db)
+	})
+
+	reportsHandler := reportshandler.New(db)
+
+	r.HandleFunc("/reports/unpaid", func(w http.ResponseWriter, r *http.Request) {
+		getUnpaidReport(w, r, reportsHandler)
+	})
+
+	r.HandleFunc("/reports/addresses", func(w http.ResponseWriter, r *http.Request) {
+		getAddressReport(w, r, db)
+	})
+
+	r.HandleFunc("/reports/statuses", func(w http.ResponseWriter, r *http.Request) {
+		getStatusHistory(w, r, db)
+	})
+
+	r.HandleFunc("/reports/delivery", func(w http.ResponseWriter, r *http.Request) {
+		getDeliveryReport(w, r, reportsHandler)
+	})
+
+	r.HandleFunc("/reports/pickups", func(w http.ResponseWriter, r *http.Request) {
+		getPickupSchedule(w, r, reportsHandler)
+	})
+
 	handleSuccess(sender, fmt.Sprintf("Starting HTTP server on :%v", config.Envs.APIPort))
 
 	err = http.ListenAndServe(fmt.Sprintf(":%v", config.Envs.APIPort), r)
@@ -128,9 +255,23 @@ func fetchDataFromDB(w http.ResponseWriter, r *http.Request, storage *db.SqliteD
 		result, err = storage.GetOrdersBySearch(query, wholePhrase)
 	} else if searchType == "byCustomer" {
 		result, err = storage.GetOrdersByCustomer(query)
+	} else if searchType == "byNotes" {
+		result, err = storage.GetOrdersByNotes(query)
+	} else if searchType == "byShipment" {
+		identifierType := r.URL.Query().Get("identifierType")
+		result, err = storage.GetOrdersByShipment(query, identifierType)
+	}
+	if errors.Is(err, config.ErrUnknownIdentifier) {
+		http.Error(w, "Unknown identifierType", http.StatusBadRequest)
+		return
 	}
 	if err != nil {
 		http.Error(w, "Failed to fetch data", http.StatusInternalServerError)
+		return
+	}
+
+	if status := r.URL.Query().Get("status"); status != "" {
+		result = filterByStatus(result, status)
 	}
 
 	start := (page - 1) * limit
@@ -152,3 +293,63 @@ func fetchDataFromDB(w http.ResponseWriter, r *http.Request, storage *db.SqliteD
 
 	w.WriteHeader(http.StatusOK)
 }
+
+func checkInscription(
+	w http.ResponseWriter, r *http.Request, essentialsHandler *essentialshandler.EssentialsHandler,
+) {
+	text := r.URL.Query().Get("text")
+	customer := r.URL.Query().Get("customer")
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if text == "" {
+		http.Error(w, "Parameter text is required", http.StatusBadRequest)
+		return
+	}
+
+	matches, err := essentialsHandler.CheckInscription(text, customer)
+	if err != nil {
+		http.Error(w, "Failed to check inscription", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(matches); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func getCustomerProfile(
+	w http.ResponseWriter, r *http.Request, customersHandler *customershandler.CustomersHandler,
+) {
+	w.Header().Set("Content-Type", "application/json")
+
+	customerID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Invalid customer id", http.StatusBadRequest)
+		return
+	}
+
+	profile, err := customersHandler.GetCustomerProfile(customerID)
+	if err != nil {
+		if errors.Is(err, config.ErrNoRecordFound) {
+			http.Error(w, "Customer not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to fetch customer", http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(profile); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+func filterByStatus(orders []db.Data, status string) []db.Data {
+	filtered := []db.Data{}
+	for _, order := range orders {
+		if order.Status == status {
+			filtered = append(filtered, order)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/previewhandler"
+	"github.com/gorilla/mux"
+)
+
+func getInscriptionPreview(
+	w http.ResponseWriter, r *http.Request, previewHandler *previewhandler.PreviewHandler,
+) {
+	vars := mux.Vars(r)
+
+	rowNumber, err := strconv.Atoi(vars["row"])
+	if err != nil {
+		http.Error(w, "Invalid row number", http.StatusBadRequest)
+		return
+	}
+
+	var preview bytes.Buffer
+	if err = previewHandler.RenderPreview(&preview, vars["date"], rowNumber); err != nil {
+		if errors.Is(err, config.ErrNoRecordFound) {
+			http.Error(w, "Order not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to render preview", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	w.Write(preview.Bytes())
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/crush-on-anechka/ktn_stats/db"
+	"github.com/crush-on-anechka/ktn_stats/issueshandler"
+	"github.com/crush-on-anechka/ktn_stats/reportshandler"
+)
+
+type AddressIssue struct {
+	Date              string
+	RowNumber         int
+	DeliveryAddress   string
+	City              string
+	PostCode          string
+	AddressRegion     string
+	AddressCity       string
+	AddressStreet     string
+	AddressHouse      string
+	AddressApartment  string
+	AddressPostCode   string
+	AddressConfidence int
+	PostCodeMismatch  bool
+	OrderLink         string
+}
+
+func getAddressReport(w http.ResponseWriter, r *http.Request, storage *db.SqliteDB) {
+	from := r.URL.Query().Get("from")
+	to := r.URL.Query().Get("to")
+	status := r.URL.Query().Get("status")
+
+	orders, err := storage.GetAddressIssues(from, to, status)
+	if err != nil {
+		http.Error(w, "Failed to fetch data", http.StatusInternalServerError)
+		return
+	}
+
+	issues := make([]AddressIssue, 0, len(orders))
+	for _, order := range orders {
+		issues = append(issues, AddressIssue{
+			Date:              order.Date,
+			RowNumber:         order.RowNumber,
+			DeliveryAddress:   order.DeliveryAddress,
+			City:              order.City,
+			PostCode:          order.PostCode,
+			AddressRegion:     order.AddressRegion,
+			AddressCity:       order.AddressCity,
+			AddressStreet:     order.AddressStreet,
+			AddressHouse:      order.AddressHouse,
+			AddressApartment:  order.AddressApartment,
+			AddressPostCode:   order.AddressPostCode,
+			AddressConfidence: order.AddressConfidence,
+			PostCodeMismatch:  order.PostCodeMismatch,
+			OrderLink:         order.OrderLink,
+		})
+	}
+
+	writeJSON(w, issues)
+}
+
+func getIssues(w http.ResponseWriter, r *http.Request, storage *db.SqliteDB) {
+	query := r.URL.Query()
+
+	from, to := query.Get("from"), query.Get("to")
+	if date := query.Get("date"); date != "" {
+		from, to = date, date
+	}
+
+	issues, err := storage.GetIssues(from, to, query.Get("severity"), query.Get("rule"))
+	if err != nil {
+		http.Error(w, "Failed to fetch data", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, issues)
+}
+
+func getEngravingViolations(
+	w http.ResponseWriter, r *http.Request, issuesHandler *issueshandler.IssuesHandler,
+) {
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		http.Error(w, "Parameter date is required", http.StatusBadRequest)
+		return
+	}
+
+	violations, err := issuesHandler.GetEngravingViolations(date)
+	if err != nil {
+		http.Error(w, "Failed to fetch data", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, violations)
+}
+
+func getIngestionStats(w http.ResponseWriter, r *http.Request, storage *db.SqliteDB) {
+	stats, err := storage.GetIngestionStats(r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	if err != nil {
+		http.Error(w, "Failed to fetch data", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, stats)
+}
+
+func getUnpaidReport(
+	w http.ResponseWriter, r *http.Request, reportsHandler *reportshandler.ReportsHandler,
+) {
+	query := r.URL.Query()
+
+	report, err := reportsHandler.GetUnpaidReport(query.Get("from"), query.Get("to"), query.Get("status"))
+	if err != nil {
+		http.Error(w, "Failed to fetch data", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, report)
+}
+
+func getDeliveryReport(
+	w http.ResponseWriter, r *http.Request, reportsHandler *reportshandler.ReportsHandler,
+) {
+	query := r.URL.Query()
+
+	report, err := reportsHandler.GetDeliveryBreakdown(query.Get("from"), query.Get("to"), query.Get("status"))
+	if err != nil {
+		http.Error(w, "Failed to fetch data", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, report)
+}
+
+func getPickupSchedule(
+	w http.ResponseWriter, r *http.Request, reportsHandler *reportshandler.ReportsHandler,
+) {
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		http.Error(w, "date parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	schedule, err := reportsHandler.GetPickupSchedule(date, r.URL.Query().Get("status"))
+	if err != nil {
+		http.Error(w, "Failed to fetch data", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, schedule)
+}
+
+func getStatusHistory(w http.ResponseWriter, r *http.Request, storage *db.SqliteDB) {
+	query := r.URL.Query()
+
+	changes, err := storage.GetStatusHistory(query.Get("from"), query.Get("to"), query.Get("status"))
+	if err != nil {
+		http.Error(w, "Failed to fetch data", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, changes)
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
@@ -7,9 +7,10 @@ import (
 
 	"github.com/crush-on-anechka/ktn_stats/config"
 	"github.com/crush-on-anechka/ktn_stats/messagesender"
+	"github.com/crush-on-anechka/ktn_stats/tasks"
 )
 
-func initFlags() (*bool, *bool, map[string]*bool, *string) {
+func initFlags() (*bool, *bool, map[string]*bool, map[string]*string) {
 	taskMode := flag.Bool("task", false, "Run cron task")
 	webMode := flag.Bool("web", false, "Run as web server")
 
@@ -20,13 +21,24 @@ func initFlags() (*bool, *bool, map[string]*bool, *string) {
 		"store_latest":      flag.Bool("store_latest", false, "Fetch and store latest spreadsheet"),
 		"store_all":         flag.Bool("store_all", false, "Fetch and store all spreadsheets"),
 		"update_essentials": flag.Bool("update_essentials", false, "Re-process essential fields"),
+		"check_issues":      flag.Bool("check_issues", false, "Check data quality of all orders"),
+		"notify_unpaid":     flag.Bool("notify_unpaid", false, "Send unpaid orders of recent batches"),
+		"resolve_customers": flag.Bool("resolve_customers", false, "Re-resolve customers of all orders"),
+		"merge_customers":   flag.Bool("merge_customers", false, "Merge customers by two keys"),
+		"split_customers":   flag.Bool("split_customers", false, "Split customers by two keys"),
+		"build_orders":      flag.Bool("build_orders", false, "Group stored rows into orders"),
+		"check_essentials_rules": flag.Bool(
+			"check_essentials_rules", false, "Show rows excluded by essentials rules"),
 	}
 
-	year := flag.String("year", "", "Specify year for storing spreadsheet data")
+	taskArgs := map[string]*string{
+		"year": flag.String("year", "", "Specify year for storing spreadsheet data"),
+		"keys": flag.String("keys", "", "Specify two comma separated customer keys"),
+	}
 
 	flag.Parse()
 
-	return taskMode, webMode, taskFlags, year
+	return taskMode, webMode, taskFlags, taskArgs
 }
 
 func handleError(err error, sender *messagesender.Sender, message string) {
@@ -44,6 +56,19 @@ func handleSuccess(sender *messagesender.Sender, message string) {
 	weeklyCheck(sender, message)
 }
 
+// handleReport sends a non-empty task report to Telegram
+func handleReport(sender *messagesender.Sender, report string) {
+	if report == "" {
+		return
+	}
+
+	log.Println(report)
+	errSender := sender.SendMessageToTelegramBot(report)
+	if errSender != nil {
+		log.Println("Failed to send message to Telegram:", errSender)
+	}
+}
+
 func weeklyCheck(sender *messagesender.Sender, message string) {
 	today := time.Now().Weekday()
 	currentHour := time.Now().Hour()
@@ -52,7 +77,13 @@ func weeklyCheck(sender *messagesender.Sender, message string) {
 		currentHour >= config.WeeklyCheckHourFrom &&
 		currentHour < config.WeeklyCheckHourTo {
 
-		message = "Weekly check!\n" + message
+		digest, err := tasks.TrendsDigest()
+		if err != nil {
+			log.Println("Failed to build trends digest:", err)
+			digest = "Weekly check!"
+		}
+
+		message = digest + "\n\n" + message
 		errSender := sender.SendMessageToTelegramBot(message)
 
 		if errSender != nil {
@@ -7,11 +7,31 @@ import (
 )
 
 const (
-	GreedyRequestTimeout = 50 * time.Millisecond
-	SafeRequestTimeout   = 1200 * time.Millisecond
-	StartYear            = 2018
-	SheetParseRange      = "A1:AA700"
-	SQLitePath           = "./ktn.db"
+	GreedyRequestTimeout   = 50 * time.Millisecond
+	SafeRequestTimeout     = 1200 * time.Millisecond
+	StartYear              = 2018
+	SheetParseRange        = "A1:AA700"
+	SQLitePath             = "./ktn.db"
+	SettingsFile           = "./settings.json"
+	AddressLowConfidence   = 60
+	UnpaidReportBatches    = 5
+	UnpaidReportOrders     = 15
+	HeaderSearchRows       = 5
+	StatusColorTolerance   = 40
+	PhraseMinWords         = 2
+	PhraseMaxWords         = 5
+	EssentialsDefaultLimit = 50
+	TrendsDefaultDays      = 28
+	TrendsMinSupport       = 3
+	TrendsDigestLimit      = 10
+	EssentialsRuleSamples  = 10
+	EntityTopValues        = 10
+	LengthHistogramBucket  = 5
+	SpecialCharsLimit      = 20
+	EngravingReportLimit   = 20
+	IssuesSummaryDates     = 20
+	TelegramMessageLimit   = 4000 // Telegram allows 4096 UTF-16 code units, leave room for emoji
+	DateLayout             = "2006.01.02"
 )
 
 var (
@@ -24,23 +44,94 @@ var (
 		"Ring":                true,
 		"InscriptionBracelet": true,
 	}
+	ShipmentIdentifierTypes  = []string{"boxberry", "pickup", "pvz", "email", "postcode"}
+	ShipmentIdentifierFields = map[string]string{
+		"boxberry": "BoxberryNumber",
+		"pickup":   "PickupNumber",
+		"pvz":      "PVZ",
+		"email":    "Email",
+		"postcode": "PostCode",
+	}
+	// PreviewTemplates map product types to inscription preview templates, other types are
+	// previewed as a plate
+	PreviewTemplates = map[string]string{
+		"КОЛЬЦО":    PreviewTemplateRing,
+		"ОБРУЧАЛКИ": PreviewTemplateRing,
+		"ПОДВЕСКА":  PreviewTemplatePendant,
+		"ЖЕТОН":     PreviewTemplatePendant,
+		"АДРЕСНИК":  PreviewTemplatePendant,
+		"БРАСЛЕТ":   PreviewTemplateBracelet,
+	}
 	WeeklyCheckWeekday  = time.Monday
 	WeeklyCheckHourFrom = 9
 	WeeklyCheckHourTo   = 12
 )
 
 const (
-	DataTableName         = "Data"
-	DatesTableName        = "Dates"
-	SheetNameAvailability = "НАЛИЧИЕ"
-	SheetNameUrgentOrders = "Срочные заказы"
-	SheetAvailabilityDate = "00.00"
-	SheetUrgentOrdersDate = "01.00"
+	DataTableName              = "Data"
+	DatesTableName             = "Dates"
+	CustomersTableName         = "Customers"
+	CustomerKeysTableName      = "CustomerKeys"
+	CustomerOverridesTableName = "CustomerOverrides"
+	IssuesTableName            = "Issues"
+	OrdersTableName            = "Orders"
+	IngestionStatsTableName    = "IngestionStats"
+	StatusHistoryTableName     = "StatusHistory"
+	EssentialsTableName        = "Essentials"
+	EssentialsTotalsTableName  = "EssentialsTotals"
+	InscriptionEntitiesTable   = "InscriptionEntities"
+	EssentialKindWord          = "word"
+	EssentialKindPhrase        = "phrase"
+	EssentialKindLemma         = "lemma"
+	EssentialsRuleInclude      = "include"
+	EssentialsRuleExclude      = "exclude"
+	SheetNameAvailability      = "НАЛИЧИЕ"
+	SheetNameUrgentOrders      = "Срочные заказы"
+	SheetAvailabilityDate      = "00.00"
+	SheetUrgentOrdersDate      = "01.00"
+	AnyIdentifier              = "any"
+	CustomerOverrideMerge      = "merge"
+	CustomerOverrideSplit      = "split"
+	SeverityError              = "error"
+	SeverityWarning            = "warning"
+	SeverityInfo               = "info"
+	DeliveryMethodCourier      = "courier"
+	DeliveryMethodPickup       = "pickup"
+	DeliveryMethodCDEK         = "cdek"
+	DeliveryMethodBoxberry     = "boxberry"
+	DeliveryMethodPost         = "post"
+	DeliveryMethodUnknown      = "unknown"
+	StatusShipped              = "shipped"
+	StatusProblem              = "problem"
+	StatusAwaitingPayment      = "awaiting_payment"
+	StatusUnknown              = "unknown"
+	EntityName                 = "name"
+	EntityDate                 = "date"
+	EntityCoordinates          = "coordinates"
+	EntityRomanNumeral         = "roman_numeral"
+	EntitySymbol               = "symbol"
+	PreviewTemplateRing        = "ring"
+	PreviewTemplatePendant     = "pendant"
+	PreviewTemplateBracelet    = "bracelet"
+	PreviewTemplatePlate       = "plate"
 )
 
 var (
 	ErrNoRecordFound       = errors.New("no record found")
+	ErrInvalidDate         = errors.New("invalid date")
+	ErrUnknownIdentifier   = errors.New("unknown identifier type")
 	DatePatternRegex       = regexp.MustCompile(`\b(\d{1,2})\.(\d{1,2})\b`)
 	LowercaseCyrillicRegex = regexp.MustCompile(`[а-я]`)
 	LettersRegex           = regexp.MustCompile(`[a-zA-Zа-яА-Я]`)
+	// engraved dates ("12.06.2015", "12/06/15") and standalone years ("2015")
+	EngravedDateRegex = regexp.MustCompile(`\b(\d{1,2})[./-](\d{1,2})[./-](\d{4}|\d{2})\b`)
+	YearRegex         = regexp.MustCompile(`\b(19\d{2}|20\d{2})\b`)
+	// coordinates as decimal degrees ("55.7558, 37.6173") or degrees, minutes and seconds
+	// ("55°45'21\"N 37°37'2\"E")
+	DecimalCoordinatesRegex = regexp.MustCompile(
+		`(-?\d{1,3}[.,]\d{3,})\s*°?\s*([NSСЮ])?[\s,;]+(-?\d{1,3}[.,]\d{3,})\s*°?\s*([EWВЗ])?`)
+	DMSCoordinatesRegex = regexp.MustCompile(
+		`(\d{1,3})°\s*(\d{1,2})['′’]\s*(?:(\d{1,2}(?:[.,]\d+)?)["″”]?)?\s*([NSСЮ])[\s,;]*` +
+			`(\d{1,3})°\s*(\d{1,2})['′’]\s*(?:(\d{1,2}(?:[.,]\d+)?)["″”]?)?\s*([EWВЗ])`)
+	RomanNumeralRegex = regexp.MustCompile(`^M{0,3}(CM|CD|D?C{0,3})(XC|XL|L?X{0,3})(IX|IV|V?I{0,3})$`)
 )
@@ -14,6 +14,7 @@ type Config struct {
 	SheetParseRange string
 	SpreadsheetIDs  []string
 	SQLitePath      string
+	SettingsFile    string
 	TelegramToken   string
 	TelegramChatID  int
 	APIPort         int
@@ -31,6 +32,7 @@ func NewConfig() Config {
 		SheetParseRange: getEnv("sheetParseRange", SheetParseRange),
 		SpreadsheetIDs:  getEnvAsSlice("spreadsheetIDString", ""),
 		SQLitePath:      getEnv("SQLitePath", SQLitePath),
+		SettingsFile:    getEnv("settingsFile", SettingsFile),
 		TelegramToken:   getEnv("telegramToken", ""),
 		TelegramChatID:  getEnvAsInt("telegramChatID", 0),
 		APIPort:         getEnvAsInt("APIPort", 8000),
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+)
+
+// Settings holds catalogue dependent rules which may change without code edits. Defaults are
+// defined below, values from settings file (settingsFile env, "./settings.json" by default)
+// override them
+type Settings struct {
+	DeliveryMethods []DeliveryMethodSynonyms `json:"deliveryMethods"`
+	// OrderGroupingFields are sheet columns which, when merged across rows, make those rows
+	// items of one order. Merges of other columns only propagate values
+	OrderGroupingFields []string `json:"orderGroupingFields"`
+	// HeaderAliases maps alternative column titles of older sheets to field names
+	HeaderAliases map[string]string `json:"headerAliases"`
+	// StatusPalette maps row background colours ("#rrggbb") to order statuses
+	StatusPalette []StatusColors `json:"statusPalette"`
+	// EssentialsRules select inscription fields counted in essentials statistics
+	EssentialsRules []EssentialsRule `json:"essentialsRules"`
+	// EngravedTypes are "Тип" values of products with engraving, their inscriptions are checked
+	// by data quality rules and engraving constraints
+	EngravedTypes []string `json:"engravedTypes"`
+	// LemmaDictionaryFile is an optional text file mapping word forms to lemmas, words missing
+	// from it are normalized by stemmer
+	LemmaDictionaryFile string `json:"lemmaDictionaryFile"`
+	// NameDictionaryFile is an optional text file with personal names recognized in
+	// inscriptions in addition to built-in ones
+	NameDictionaryFile string `json:"nameDictionaryFile"`
+	// EngravingConstraints limit inscriptions which fit on products and our engraving font
+	EngravingConstraints []EngravingConstraint `json:"engravingConstraints"`
+}
+
+// EngravingConstraint limits inscription Field ("Кольцо", all inscription fields if empty) of
+// rows with "Тип" Type and "Вид" matching SQL LIKE SubtypePattern (any if empty). Zero limits
+// aren't checked. If AllowedClasses are set, characters of other classes (cyrillic, latin,
+// greek, digit, space, punctuation, emoji, symbol, other) are allowed only if listed in
+// AllowedChars. Every matching constraint is checked
+type EngravingConstraint struct {
+	Type           string   `json:"type"`
+	SubtypePattern string   `json:"subtypePattern"`
+	Field          string   `json:"field"`
+	MaxLineLength  int      `json:"maxLineLength"`
+	MaxLines       int      `json:"maxLines"`
+	AllowedClasses []string `json:"allowedClasses"`
+	AllowedChars   string   `json:"allowedChars"`
+}
+
+// EssentialsRule matches rows by "Тип" (any of Types) and "Вид" (any of SQL LIKE
+// SubtypePatterns, case sensitive for cyrillic). Empty Types or SubtypePatterns match any row.
+// Exclude rules drop inscription Fields ("Надпись", "Нижний торец", ..., all if empty) of
+// matched rows from essentials, include rules keep them even if an exclude rule matches
+type EssentialsRule struct {
+	Name            string   `json:"name"`
+	Action          string   `json:"action"`
+	Types           []string `json:"types"`
+	SubtypePatterns []string `json:"subtypePatterns"`
+	Fields          []string `json:"fields"`
+}
+
+// StatusColors lists background colours managers use for a status. A row colour matches if it
+// is close to one of the colours, so similar shades are recognized too
+type StatusColors struct {
+	Status string   `json:"status"`
+	Colors []string `json:"colors"`
+}
+
+// DeliveryMethodSynonyms maps lowercase substrings of "Способ доставки" to a delivery method.
+// Methods are checked in order, so more specific ones must go first
+type DeliveryMethodSynonyms struct {
+	Method   string   `json:"method"`
+	Synonyms []string `json:"synonyms"`
+}
+
+var AppSettings = NewSettings(Envs.SettingsFile)
+
+func NewSettings(path string) Settings {
+	settings := defaultSettings()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Error reading settings file: %v", err)
+		}
+		return settings
+	}
+
+	if err := json.Unmarshal(data, &settings); err != nil {
+		log.Printf("Error parsing settings file, using defaults: %v", err)
+		return defaultSettings()
+	}
+
+	return settings
+}
+
+func defaultSettings() Settings {
+	return Settings{
+		DeliveryMethods: []DeliveryMethodSynonyms{
+			{Method: DeliveryMethodCourier, Synonyms: []string{"курьер", "до двери"}},
+			{Method: DeliveryMethodPickup, Synonyms: []string{"самовывоз", "сам"}},
+			{Method: DeliveryMethodCDEK, Synonyms: []string{"cdek", "сдэк", "сдек"}},
+			{Method: DeliveryMethodBoxberry, Synonyms: []string{"boxberry", "боксберри", "бокс", "бб"}},
+			{Method: DeliveryMethodPost, Synonyms: []string{"почта", "почтой", "ems", "пр"}},
+		},
+		OrderGroupingFields: []string{"Ссылка", "Соцсеть", "ФИО", "Телефон"},
+		HeaderAliases: map[string]string{
+			"ссылка на профиль": "Ссылка",
+			"профиль":           "Ссылка",
+			"имя":               "ФИО",
+			"покупатель":        "ФИО",
+			"телефон клиента":   "Телефон",
+			"email":             "e-mail",
+			"почта":             "e-mail",
+			"сумма заказа":      "Сумма",
+			"стоимость":         "Сумма",
+			"доставка":          "Способ доставки",
+			"адрес":             "Адрес доставки",
+			"доставка адрес":    "Адрес доставки",
+			"доставка способ":   "Способ доставки",
+		},
+		StatusPalette: []StatusColors{
+			{Status: StatusShipped, Colors: []string{"#b7e1cd", "#d9ead3", "#b6d7a8", "#93c47d", "#00ff00"}},
+			{Status: StatusProblem, Colors: []string{"#f4cccc", "#ea9999", "#e06666", "#ff0000"}},
+			{Status: StatusAwaitingPayment, Colors: []string{"#fff2cc", "#ffe599", "#ffd966", "#ffff00"}},
+		},
+		EssentialsRules: []EssentialsRule{
+			{
+				Name:   "types without engraving",
+				Action: EssentialsRuleExclude,
+				Types: []string{
+					"КОЛЬЦО С КАМНЕМ", "КОЛЬЦО-СИМВОЛ", "ПОДВЕСКА С КАМНЕМ", "СЕРЬГИ С КАМНЯМИ", "СЕРЬГИ",
+					"СИМВОЛ-БРАСЛЕТ", "СИМВОЛ-ПОДВЕСКА", "ШНУРОК", "ЦЕПОЧКА", "ШНУРОК ДЛЯ АДРЕСНИКА",
+				},
+			},
+			{
+				Name:   "figures without engraving",
+				Action: EssentialsRuleExclude,
+				SubtypePatterns: []string{
+					"капелька%", "%апки%", "%ракон%", "%убики%", "%апсула%", "писюн%", "член%", "%нгел%",
+				},
+			},
+		},
+		EngravedTypes: []string{
+			"КОЛЬЦО", "ПОДВЕСКА", "БРАСЛЕТ", "АДРЕСНИК", "ЖЕТОН", "БРЕЛОК", "ЗАПОНКИ", "ОБРУЧАЛКИ",
+		},
+		EngravingConstraints: []EngravingConstraint{
+			{Field: "Кольцо", MaxLineLength: 30, MaxLines: 1},
+			{Field: "Браслет надпись", MaxLineLength: 40, MaxLines: 2},
+			{Field: "Верхний торец", MaxLineLength: 25, MaxLines: 1},
+			{Field: "Нижний торец", MaxLineLength: 25, MaxLines: 1},
+			{
+				AllowedClasses: []string{"cyrillic", "latin", "digit", "space", "punctuation"},
+				AllowedChars:   "♥∞",
+			},
+		},
+	}
+}
@@ -0,0 +1,215 @@
+package customershandler
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/db"
+)
+
+type CustomersHandler struct {
+	storage *db.SqliteDB
+}
+
+func New(storage *db.SqliteDB) *CustomersHandler {
+	return &CustomersHandler{storage: storage}
+}
+
+// ResolveCustomers links all stored orders into customers by normalized phone, social link
+// and email using union-find. Previously assigned customer IDs are kept whenever possible,
+// merge/split overrides from CustomerOverrides table are applied on every run
+func (handler *CustomersHandler) ResolveCustomers() error {
+	orders, err := handler.storage.GetAllOrders()
+	if err != nil {
+		return fmt.Errorf("failed to fetch orders from db: %w", err)
+	}
+
+	overrides, err := handler.storage.GetCustomerOverrides()
+	if err != nil {
+		return fmt.Errorf("failed to fetch customer overrides from db: %w", err)
+	}
+
+	previousKeys, err := handler.storage.GetCustomerKeys()
+	if err != nil {
+		return fmt.Errorf("failed to fetch customer keys from db: %w", err)
+	}
+
+	uf := newUnionFind()
+	var splits [][2]string
+
+	for _, override := range overrides {
+		switch override.Kind {
+		case config.CustomerOverrideMerge:
+			uf.union(override.KeyA, override.KeyB)
+		case config.CustomerOverrideSplit:
+			splits = append(splits, [2]string{override.KeyA, override.KeyB})
+		}
+	}
+
+	orderKeys := make([][]string, len(orders))
+	seenKeys := make(map[string]bool)
+
+	for i, order := range orders {
+		keys := CustomerKeys(order)
+		orderKeys[i] = keys
+
+		for _, key := range keys {
+			seenKeys[key] = true
+			if uf.find(key) != uf.find(keys[0]) && !uf.violatesSplit(key, keys[0], splits) {
+				uf.union(key, keys[0])
+			}
+		}
+	}
+
+	clusters := make(map[string][]string)
+	for key := range seenKeys {
+		root := uf.find(key)
+		clusters[root] = append(clusters[root], key)
+	}
+
+	customerIDs := assignCustomerIDs(clusters, previousKeys)
+
+	customerKeys := make(map[string]int)
+	for root, keys := range clusters {
+		for _, key := range keys {
+			customerKeys[key] = customerIDs[root]
+		}
+	}
+
+	customersByID := make(map[int]*db.Customer)
+	orderCustomers := make([]db.OrderCustomer, 0, len(orders))
+
+	// orders are sorted by date, so contacts of the most recent order are kept
+	for i, order := range orders {
+		if len(orderKeys[i]) == 0 {
+			continue
+		}
+
+		customerID := customerIDs[uf.find(orderKeys[i][0])]
+
+		orderCustomers = append(orderCustomers, db.OrderCustomer{
+			Date:       order.Date,
+			RowNumber:  order.RowNumber,
+			CustomerID: customerID,
+		})
+
+		customer, exists := customersByID[customerID]
+		if !exists {
+			customer = &db.Customer{ID: customerID}
+			customersByID[customerID] = customer
+		}
+		updateCustomerContacts(customer, order)
+	}
+
+	customers := make([]db.Customer, 0, len(customersByID))
+	for _, customer := range customersByID {
+		customers = append(customers, *customer)
+	}
+
+	tx, err := handler.storage.BeginTransaction()
+	if err != nil {
+		return err
+	}
+
+	if err = handler.storage.ReplaceCustomersWithTx(tx, customers, customerKeys); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to store customers: %w", err)
+	}
+
+	if err = handler.storage.UpdateCustomerIDsWithTx(tx, orderCustomers); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to store customer IDs: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// AddOverride stores a merge or split override for two customer keys
+func (handler *CustomersHandler) AddOverride(kind, keyA, keyB string) error {
+	if kind != config.CustomerOverrideMerge && kind != config.CustomerOverrideSplit {
+		return fmt.Errorf("unknown override kind %s", kind)
+	}
+	if keyA == "" || keyB == "" || keyA == keyB {
+		return fmt.Errorf("override requires two different customer keys")
+	}
+
+	return handler.storage.CreateCustomerOverride(db.CustomerOverride{
+		Kind: kind,
+		KeyA: keyA,
+		KeyB: keyB,
+	})
+}
+
+// assignCustomerIDs maps every cluster root to a customer ID. A cluster reuses the ID which
+// most of its keys had before, so IDs stay stable across re-ingestion
+func assignCustomerIDs(clusters map[string][]string, previousKeys map[string]int) map[string]int {
+	roots := make([]string, 0, len(clusters))
+	maxID := 0
+
+	for root := range clusters {
+		roots = append(roots, root)
+	}
+	for _, customerID := range previousKeys {
+		if customerID > maxID {
+			maxID = customerID
+		}
+	}
+
+	// bigger clusters claim their previous IDs first
+	sort.Slice(roots, func(i, j int) bool {
+		if len(clusters[roots[i]]) != len(clusters[roots[j]]) {
+			return len(clusters[roots[i]]) > len(clusters[roots[j]])
+		}
+		return roots[i] < roots[j]
+	})
+
+	customerIDs := make(map[string]int)
+	usedIDs := make(map[int]bool)
+
+	for _, root := range roots {
+		votes := make(map[int]int)
+		for _, key := range clusters[root] {
+			if customerID, exists := previousKeys[key]; exists && !usedIDs[customerID] {
+				votes[customerID]++
+			}
+		}
+
+		bestID := 0
+		for customerID, count := range votes {
+			if bestID == 0 || count > votes[bestID] || (count == votes[bestID] && customerID < bestID) {
+				bestID = customerID
+			}
+		}
+
+		if bestID == 0 {
+			maxID++
+			bestID = maxID
+		}
+
+		usedIDs[bestID] = true
+		customerIDs[root] = bestID
+	}
+
+	return customerIDs
+}
+
+func updateCustomerContacts(customer *db.Customer, order db.Data) {
+	if order.FullName != "" {
+		customer.FullName = order.FullName
+	}
+	if order.Phone != "" {
+		customer.Phone = order.Phone
+	}
+	if order.CustomerLink != "" {
+		customer.CustomerLink = order.CustomerLink
+		customer.Socials = order.Socials
+	}
+	if order.Email != "" {
+		customer.Email = order.Email
+	}
+}
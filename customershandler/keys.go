@@ -0,0 +1,109 @@
+package customershandler
+
+import (
+	"net/mail"
+	"strings"
+
+	"github.com/crush-on-anechka/ktn_stats/db"
+)
+
+const (
+	phoneKeyPrefix = "phone:"
+	linkKeyPrefix  = "link:"
+	emailKeyPrefix = "email:"
+)
+
+// CustomerKeys returns normalized identifiers of an order's customer which are used to link
+// orders together, eg "phone:+79161234567", "link:vk/id123" or "email:name@mail.ru".
+// Links which are not recognized as social network profiles are compared as plain URLs
+func CustomerKeys(order db.Data) []string {
+	var keys []string
+
+	if order.PhoneNormalized != "" {
+		keys = append(keys, phoneKeyPrefix+order.PhoneNormalized)
+	}
+	if order.SocialHandle != "" {
+		keys = append(keys, linkKeyPrefix+order.SocialNetwork+"/"+order.SocialHandle)
+	} else if link := normalizeLink(order.CustomerLink); link != "" {
+		keys = append(keys, linkKeyPrefix+link)
+	}
+	if email := normalizeEmail(order.Email); email != "" {
+		keys = append(keys, emailKeyPrefix+email)
+	}
+
+	return keys
+}
+
+func normalizeLink(link string) string {
+	link = strings.ToLower(strings.TrimSpace(link))
+
+	if !strings.Contains(link, ".") || !strings.Contains(link, "/") {
+		return ""
+	}
+
+	link = strings.TrimPrefix(strings.TrimPrefix(link, "https://"), "http://")
+	link = strings.TrimPrefix(strings.TrimPrefix(link, "www."), "m.")
+	if idx := strings.IndexAny(link, "?#"); idx >= 0 {
+		link = link[:idx]
+	}
+
+	return strings.TrimRight(link, "/")
+}
+
+func normalizeEmail(email string) string {
+	address, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(address.Address)
+}
+
+type unionFind struct {
+	parent map[string]string
+}
+
+func newUnionFind() *unionFind {
+	return &unionFind{parent: make(map[string]string)}
+}
+
+func (uf *unionFind) add(key string) {
+	if _, exists := uf.parent[key]; !exists {
+		uf.parent[key] = key
+	}
+}
+
+func (uf *unionFind) find(key string) string {
+	uf.add(key)
+	for uf.parent[key] != key {
+		uf.parent[key] = uf.parent[uf.parent[key]]
+		key = uf.parent[key]
+	}
+	return key
+}
+
+func (uf *unionFind) union(keyA, keyB string) {
+	rootA, rootB := uf.find(keyA), uf.find(keyB)
+	if rootA == rootB {
+		return
+	}
+	if rootA < rootB {
+		uf.parent[rootB] = rootA
+	} else {
+		uf.parent[rootA] = rootB
+	}
+}
+
+// violatesSplit reports whether joining sets of keyA and keyB would put two keys of any
+// split override into the same customer
+func (uf *unionFind) violatesSplit(keyA, keyB string, splits [][2]string) bool {
+	rootA, rootB := uf.find(keyA), uf.find(keyB)
+
+	for _, split := range splits {
+		splitRootA, splitRootB := uf.find(split[0]), uf.find(split[1])
+		if (splitRootA == rootA && splitRootB == rootB) || (splitRootA == rootB && splitRootB == rootA) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,123 @@
+package customershandler
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/crush-on-anechka/ktn_stats/db"
+	"github.com/crush-on-anechka/ktn_stats/fieldparser"
+)
+
+const favouriteTypesLimit = 3
+
+type TypeCount struct {
+	Type  string
+	Count int
+}
+
+type CustomerProfile struct {
+	ID             int
+	FullNames      []string
+	Phones         []string
+	CustomerLinks  []string
+	Emails         []string
+	Addresses      []string
+	Keys           []string
+	OrdersCount    int
+	FirstOrderDate string
+	LastOrderDate  string
+	TotalSum       float64
+	FavouriteTypes []TypeCount
+	Orders         []db.Data
+}
+
+// GetCustomerProfile collects all known contacts and order statistics of a resolved customer.
+// Statistics are counted over Orders table, Orders field lists items (rows) of those orders
+func (handler *CustomersHandler) GetCustomerProfile(customerID int) (*CustomerProfile, error) {
+	if _, err := handler.storage.GetCustomerByID(customerID); err != nil {
+		return nil, err
+	}
+
+	keys, err := handler.storage.GetCustomerKeysByID(customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch customer keys from db: %w", err)
+	}
+
+	items, err := handler.storage.GetOrdersByCustomerID(customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch customer items from db: %w", err)
+	}
+
+	orders, err := handler.storage.GetCustomerOrders(customerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch customer orders from db: %w", err)
+	}
+
+	profile := &CustomerProfile{
+		ID:          customerID,
+		Keys:        keys,
+		OrdersCount: len(orders),
+		Orders:      items,
+	}
+
+	typesCount := make(map[string]int)
+
+	for _, item := range items {
+		profile.FullNames = appendUnique(profile.FullNames, item.FullName)
+		profile.Phones = appendUnique(profile.Phones, item.Phone)
+		profile.CustomerLinks = appendUnique(profile.CustomerLinks, item.CustomerLink)
+		profile.Emails = appendUnique(profile.Emails, item.Email)
+		profile.Addresses = appendUnique(profile.Addresses, item.DeliveryAddress)
+
+		if item.Type != "" {
+			typesCount[item.Type]++
+		}
+	}
+
+	totalSumKopecks := 0
+
+	for _, order := range orders {
+		totalSumKopecks += order.SumKopecks
+
+		if !fieldparser.IsBatchDate(order.Date) {
+			continue
+		}
+		if profile.FirstOrderDate == "" || order.Date < profile.FirstOrderDate {
+			profile.FirstOrderDate = order.Date
+		}
+		if order.Date > profile.LastOrderDate {
+			profile.LastOrderDate = order.Date
+		}
+	}
+
+	profile.TotalSum = float64(totalSumKopecks) / 100
+
+	for orderType, count := range typesCount {
+		profile.FavouriteTypes = append(profile.FavouriteTypes, TypeCount{orderType, count})
+	}
+
+	sort.Slice(profile.FavouriteTypes, func(i, j int) bool {
+		if profile.FavouriteTypes[i].Count != profile.FavouriteTypes[j].Count {
+			return profile.FavouriteTypes[i].Count > profile.FavouriteTypes[j].Count
+		}
+		return profile.FavouriteTypes[i].Type < profile.FavouriteTypes[j].Type
+	})
+
+	if len(profile.FavouriteTypes) > favouriteTypesLimit {
+		profile.FavouriteTypes = profile.FavouriteTypes[:favouriteTypesLimit]
+	}
+
+	return profile, nil
+}
+
+func appendUnique(values []string, value string) []string {
+	if value == "" {
+		return values
+	}
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
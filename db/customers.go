@@ -0,0 +1,262 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+)
+
+func (sqlite *SqliteDB) initCustomersTables() error {
+	createTablesSQL := []string{
+		fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS %s (
+				ID INTEGER PRIMARY KEY,
+				FullName TEXT,
+				Phone TEXT,
+				CustomerLink TEXT,
+				Socials TEXT,
+				Email TEXT
+			);
+		`, config.CustomersTableName),
+		fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS %s (
+				Key TEXT PRIMARY KEY,
+				CustomerID INTEGER,
+				FOREIGN KEY (CustomerID) REFERENCES %s(ID)
+			);
+		`, config.CustomerKeysTableName, config.CustomersTableName),
+		fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS %s (
+				Kind TEXT,
+				KeyA TEXT,
+				KeyB TEXT,
+				PRIMARY KEY (Kind, KeyA, KeyB)
+			);
+		`, config.CustomerOverridesTableName),
+	}
+
+	for _, createTableSQL := range createTablesSQL {
+		if _, err := sqlite.DB.Exec(createTableSQL); err != nil {
+			return fmt.Errorf("failed to create customers tables: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetAllOrders fetches every stored row of Data table
+func (sqlite *SqliteDB) GetAllOrders() ([]Data, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s ORDER BY Date ASC, RowNumber ASC;",
+		dataColumns(), config.DataTableName)
+
+	return executeQuery(sqlite, query)
+}
+
+// GetCustomerKeys returns a map of customer keys to customer IDs resolved previously
+func (sqlite *SqliteDB) GetCustomerKeys() (map[string]int, error) {
+	query := fmt.Sprintf("SELECT Key, CustomerID FROM %s;", config.CustomerKeysTableName)
+
+	rows, err := sqlite.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	customerKeys := make(map[string]int)
+
+	for rows.Next() {
+		var key string
+		var customerID int
+		if err = rows.Scan(&key, &customerID); err != nil {
+			return nil, err
+		}
+		customerKeys[key] = customerID
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return customerKeys, nil
+}
+
+func (sqlite *SqliteDB) GetCustomerOverrides() ([]CustomerOverride, error) {
+	query := fmt.Sprintf("SELECT Kind, KeyA, KeyB FROM %s;", config.CustomerOverridesTableName)
+
+	rows, err := sqlite.DB.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var overrides []CustomerOverride
+
+	for rows.Next() {
+		var override CustomerOverride
+		if err = rows.Scan(&override.Kind, &override.KeyA, &override.KeyB); err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, override)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return overrides, nil
+}
+
+func (sqlite *SqliteDB) CreateCustomerOverride(override CustomerOverride) error {
+	insertSQL := fmt.Sprintf("INSERT OR REPLACE INTO %s (Kind, KeyA, KeyB) VALUES (?, ?, ?)",
+		config.CustomerOverridesTableName)
+
+	statement, err := sqlite.DB.Prepare(insertSQL)
+	if err != nil {
+		return fmt.Errorf("failed to prepare SQL statement: %w", err)
+	}
+	defer statement.Close()
+
+	_, err = statement.Exec(override.Kind, override.KeyA, override.KeyB)
+	if err != nil {
+		return fmt.Errorf("failed to insert data: %w", err)
+	}
+
+	return nil
+}
+
+// ReplaceCustomersWithTx rewrites Customers and CustomerKeys tables with the result of
+// customer resolution
+func (sqlite *SqliteDB) ReplaceCustomersWithTx(
+	tx *sql.Tx, customers []Customer, customerKeys map[string]int,
+) error {
+
+	for _, tableName := range []string{config.CustomerKeysTableName, config.CustomersTableName} {
+		if _, err := tx.Exec(fmt.Sprintf("DELETE FROM %s;", tableName)); err != nil {
+			return fmt.Errorf("failed to clear table %s: %w", tableName, err)
+		}
+	}
+
+	insertCustomerSQL := fmt.Sprintf(
+		"INSERT INTO %s (ID, FullName, Phone, CustomerLink, Socials, Email) VALUES (?, ?, ?, ?, ?, ?)",
+		config.CustomersTableName)
+
+	customerStmt, err := tx.Prepare(insertCustomerSQL)
+	if err != nil {
+		return fmt.Errorf("failed to prepare SQL statement: %w", err)
+	}
+	defer customerStmt.Close()
+
+	for _, customer := range customers {
+		_, err = customerStmt.Exec(customer.ID, customer.FullName, customer.Phone,
+			customer.CustomerLink, customer.Socials, customer.Email)
+		if err != nil {
+			return fmt.Errorf("failed to execute SQL statement: %w", err)
+		}
+	}
+
+	insertKeySQL := fmt.Sprintf("INSERT INTO %s (Key, CustomerID) VALUES (?, ?)",
+		config.CustomerKeysTableName)
+
+	keyStmt, err := tx.Prepare(insertKeySQL)
+	if err != nil {
+		return fmt.Errorf("failed to prepare SQL statement: %w", err)
+	}
+	defer keyStmt.Close()
+
+	for key, customerID := range customerKeys {
+		if _, err = keyStmt.Exec(key, customerID); err != nil {
+			return fmt.Errorf("failed to execute SQL statement: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (sqlite *SqliteDB) UpdateCustomerIDsWithTx(tx *sql.Tx, orderCustomers []OrderCustomer) error {
+	resetSQL := fmt.Sprintf("UPDATE %s SET CustomerID = 0;", config.DataTableName)
+	if _, err := tx.Exec(resetSQL); err != nil {
+		return fmt.Errorf("failed to reset customer IDs: %w", err)
+	}
+
+	updateSQL := fmt.Sprintf("UPDATE %s SET CustomerID = ? WHERE Date = ? AND RowNumber = ?",
+		config.DataTableName)
+
+	statement, err := tx.Prepare(updateSQL)
+	if err != nil {
+		return fmt.Errorf("failed to prepare SQL statement: %w", err)
+	}
+	defer statement.Close()
+
+	for _, orderCustomer := range orderCustomers {
+		_, err = statement.Exec(orderCustomer.CustomerID, orderCustomer.Date, orderCustomer.RowNumber)
+		if err != nil {
+			return fmt.Errorf("failed to update data: %w", err)
+		}
+	}
+
+	// an order belongs to the customer of its first row
+	updateOrdersSQL := fmt.Sprintf(
+		`UPDATE %s SET CustomerID = COALESCE(
+			(SELECT CustomerID FROM %s WHERE Date = %s.Date AND RowNumber = %s.FirstRow), 0);`,
+		config.OrdersTableName, config.DataTableName, config.OrdersTableName, config.OrdersTableName)
+
+	if _, err = tx.Exec(updateOrdersSQL); err != nil {
+		return fmt.Errorf("failed to update orders customer IDs: %w", err)
+	}
+
+	return nil
+}
+
+func (sqlite *SqliteDB) GetCustomerByID(customerID int) (Customer, error) {
+	var customer Customer
+	query := fmt.Sprintf(
+		"SELECT ID, FullName, Phone, CustomerLink, Socials, Email FROM %s WHERE ID = ? LIMIT 1;",
+		config.CustomersTableName)
+
+	err := sqlite.DB.QueryRow(query, customerID).Scan(&customer.ID, &customer.FullName,
+		&customer.Phone, &customer.CustomerLink, &customer.Socials, &customer.Email)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return customer, config.ErrNoRecordFound
+		}
+		return customer, err
+	}
+
+	return customer, nil
+}
+
+func (sqlite *SqliteDB) GetCustomerKeysByID(customerID int) ([]string, error) {
+	query := fmt.Sprintf("SELECT Key FROM %s WHERE CustomerID = ? ORDER BY Key;",
+		config.CustomerKeysTableName)
+
+	rows, err := sqlite.DB.Query(query, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+
+	for rows.Next() {
+		var key string
+		if err = rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (sqlite *SqliteDB) GetOrdersByCustomerID(customerID int) ([]Data, error) {
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE CustomerID = ? ORDER BY Date DESC, RowNumber ASC;",
+		dataColumns(), config.DataTableName)
+
+	return executeQuery(sqlite, query, customerID)
+}
@@ -5,9 +5,9 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
-	"unicode"
 
 	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/fieldparser"
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -30,7 +30,8 @@ func (sqlite *SqliteDB) Init() error {
 			Date TEXT PRIMARY KEY,
 			Hash TEXT,
 			Words JSON,
-			Phrases JSON
+			Phrases JSON,
+			Lemmas JSON
 		);
 	`, config.DatesTableName)
 
@@ -39,21 +40,17 @@ func (sqlite *SqliteDB) Init() error {
 		return fmt.Errorf("failed to create table %s: %w", config.DatesTableName, err)
 	}
 
+	if err = sqlite.addMissingColumns(config.DatesTableName, reflect.TypeOf(dateColumns{})); err != nil {
+		return err
+	}
+
 	t := reflect.TypeOf(Data{})
 	createTableSQL := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (", config.DataTableName)
 
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
 		fieldName := field.Name
-		fieldType := field.Type.Kind()
-
-		var sqlType string
-		switch fieldType {
-		case reflect.String:
-			sqlType = "TEXT"
-		case reflect.Int, reflect.Int32, reflect.Int64:
-			sqlType = "INTEGER"
-		}
+		sqlType := getSQLType(field.Type.Kind())
 
 		createTableSQL += fmt.Sprintf("%s %s", fieldName, sqlType)
 		if i < t.NumField()-1 {
@@ -79,9 +76,114 @@ func (sqlite *SqliteDB) Init() error {
 		return fmt.Errorf("failed to create table %s: %w", config.DataTableName, err)
 	}
 
+	if err = sqlite.addMissingColumns(config.DataTableName, t); err != nil {
+		return err
+	}
+
+	for _, column := range indexedColumns {
+		createIndexSQL := fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_%s ON %s (%s);",
+			strings.ToLower(config.DataTableName), strings.ToLower(column),
+			config.DataTableName, column)
+
+		if _, err = sqlite.DB.Exec(createIndexSQL); err != nil {
+			return fmt.Errorf("failed to create index on %s: %w", column, err)
+		}
+	}
+
+	if err = sqlite.initCustomersTables(); err != nil {
+		return err
+	}
+
+	if err = sqlite.initIssuesTable(); err != nil {
+		return err
+	}
+
+	if err = sqlite.initOrdersTable(); err != nil {
+		return err
+	}
+
+	if err = sqlite.initIngestionStatsTable(); err != nil {
+		return err
+	}
+
+	if err = sqlite.initStatusHistoryTable(); err != nil {
+		return err
+	}
+
+	if err = sqlite.initEssentialsTables(); err != nil {
+		return err
+	}
+
+	if err = sqlite.initInscriptionEntitiesTable(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// addMissingColumns adds columns for struct fields which are not yet present in an existing
+// table, so that databases created by previous versions keep working after Init
+func (sqlite *SqliteDB) addMissingColumns(tableName string, t reflect.Type) error {
+	rows, err := sqlite.DB.Query(fmt.Sprintf("PRAGMA table_info(%s);", tableName))
+	if err != nil {
+		return fmt.Errorf("failed to get columns of table %s: %w", tableName, err)
+	}
+	defer rows.Close()
+
+	existingColumns := make(map[string]bool)
+
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			columnType string
+			notNull    int
+			dfltValue  sql.NullString
+			primaryKey int
+		)
+		if err = rows.Scan(&cid, &name, &columnType, &notNull, &dfltValue, &primaryKey); err != nil {
+			return fmt.Errorf("failed to scan columns of table %s: %w", tableName, err)
+		}
+		existingColumns[name] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if existingColumns[field.Name] {
+			continue
+		}
+
+		// existing rows get zero values so that they can be scanned into Data struct
+		defaultValue := "0"
+		if field.Type.Kind() == reflect.String {
+			defaultValue = "''"
+		}
+
+		alterSQL := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s DEFAULT %s;",
+			tableName, field.Name, getSQLType(field.Type.Kind()), defaultValue)
+
+		if _, err = sqlite.DB.Exec(alterSQL); err != nil {
+			return fmt.Errorf("failed to add column %s to table %s: %w", field.Name, tableName, err)
+		}
+	}
+
 	return nil
 }
 
+func getSQLType(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "TEXT"
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return "INTEGER"
+	}
+	return ""
+}
+
 func (sqlite *SqliteDB) BeginTransaction() (*sql.Tx, error) {
 	tx, err := sqlite.DB.Begin()
 	if err != nil {
@@ -310,56 +412,52 @@ func (sqlite *SqliteDB) DeleteDataByDateWithTx(tx *sql.Tx, date string) error {
 	return nil
 }
 
-// GetEssentialValues fetches values from all relevant fields containing inscriptions
-func (sqlite *SqliteDB) GetInscriptionsByDate(date string) ([]string, error) {
-	query := fmt.Sprintf(
-		`SELECT Inscription, EdgeLower, EdgeUpper, Pendant, Ring, InscriptionBracelet
-		FROM %s
-		WHERE Date = ?
-		AND Type NOT IN (
-			'КОЛЬЦО С КАМНЕМ', 'КОЛЬЦО-СИМВОЛ', 'ПОДВЕСКА С КАМНЕМ', 'СЕРЬГИ С КАМНЯМИ', 'СЕРЬГИ',
-			'СИМВОЛ-БРАСЛЕТ', 'СИМВОЛ-ПОДВЕСКА', 'ШНУРОК', 'ЦЕПОЧКА', 'ШНУРОК ДЛЯ АДРЕСНИКА'
-		)
-		AND SubType NOT LIKE 'капелька%%'
-		AND SubType NOT LIKE '%%апки%%'
-		AND SubType NOT LIKE '%%ракон%%'
-		AND SubType NOT LIKE '%%убики%%'
-		AND SubType NOT LIKE '%%апсула%%'
-		AND SubType NOT LIKE 'писюн%%'
-		AND SubType NOT LIKE 'член%%'
-		AND SubType NOT LIKE '%%нгел%%'
-		;`,
-		config.DataTableName,
-	)
+// GetInscriptionsByDate returns rows of a sheet with engraved inscriptions, every row holds
+// non-empty values of all inscription fields not excluded by essentials rules from settings
+func (sqlite *SqliteDB) GetInscriptionsByDate(date string) ([]InscriptionRow, error) {
+	columns, args, err := inscriptionColumns(config.AppSettings.EssentialsRules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile essentials rules: %w", err)
+	}
+
+	query := fmt.Sprintf("SELECT RowNumber, OrderID, Type, %s FROM %s WHERE Date = ?;",
+		strings.Join(columns, ", "), config.DataTableName)
 
-	rows, err := sqlite.DB.Query(query, date)
+	rows, err := sqlite.DB.Query(query, append(args, date)...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var allInscriptions []string
+	var inscriptionRows []InscriptionRow
 
 	for rows.Next() {
-		values := make([]string, 6)
-		err = rows.Scan(
-			&values[0], &values[1], &values[2], &values[3], &values[4], &values[5])
-		if err != nil {
+		var row InscriptionRow
+		values := make([]string, len(inscriptionFields))
+		dest := []interface{}{&row.RowNumber, &row.OrderID, &row.Type}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err = rows.Scan(dest...); err != nil {
 			return nil, err
 		}
 
 		for _, value := range values {
 			if value != "" {
-				allInscriptions = append(allInscriptions, value)
+				row.Inscriptions = append(row.Inscriptions, value)
 			}
 		}
+
+		if len(row.Inscriptions) > 0 {
+			inscriptionRows = append(inscriptionRows, row)
+		}
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return allInscriptions, nil
+	return inscriptionRows, nil
 }
 
 func (sqlite *SqliteDB) UpdateWords(date, essentials string) error {
@@ -396,6 +494,40 @@ func (sqlite *SqliteDB) UpdateWordsWithTx(tx *sql.Tx, date, essentials string) e
 	return nil
 }
 
+func (sqlite *SqliteDB) UpdatePhrasesWithTx(tx *sql.Tx, date, phrases string) error {
+	updateSQL := fmt.Sprintf("UPDATE %s SET Phrases = ? WHERE Date = ?", config.DatesTableName)
+
+	statement, err := tx.Prepare(updateSQL)
+	if err != nil {
+		return fmt.Errorf("failed to prepare SQL statement: %w", err)
+	}
+	defer statement.Close()
+
+	_, err = statement.Exec(phrases, date)
+	if err != nil {
+		return fmt.Errorf("failed to update data: %w", err)
+	}
+
+	return nil
+}
+
+func (sqlite *SqliteDB) UpdateLemmasWithTx(tx *sql.Tx, date, lemmas string) error {
+	updateSQL := fmt.Sprintf("UPDATE %s SET Lemmas = ? WHERE Date = ?", config.DatesTableName)
+
+	statement, err := tx.Prepare(updateSQL)
+	if err != nil {
+		return fmt.Errorf("failed to prepare SQL statement: %w", err)
+	}
+	defer statement.Close()
+
+	_, err = statement.Exec(lemmas, date)
+	if err != nil {
+		return fmt.Errorf("failed to update data: %w", err)
+	}
+
+	return nil
+}
+
 func (sqlite *SqliteDB) GetDates() ([]string, error) {
 	query := fmt.Sprintf("SELECT Date FROM %s;", config.DatesTableName)
 
@@ -425,7 +557,7 @@ func (sqlite *SqliteDB) GetDates() ([]string, error) {
 }
 
 func (sqlite *SqliteDB) GetOrdersBySearch(searchString string, fullPhrase bool) ([]Data, error) {
-	query := fmt.Sprintf("SELECT * FROM %s WHERE Search LIKE ", config.DataTableName)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE Search LIKE ", dataColumns(), config.DataTableName)
 
 	searchStringToUpper := strings.ToUpper(searchString)
 
@@ -446,22 +578,50 @@ func (sqlite *SqliteDB) GetOrdersBySearch(searchString string, fullPhrase bool)
 	return executeQuery(sqlite, query)
 }
 
+// rawPhoneDigits strips formatting from typed phones of rows stored before phone normalization
+const rawPhoneDigits = "REPLACE(REPLACE(REPLACE(REPLACE(REPLACE(" +
+	"Phone, ' ', ''), '-', ''), '(', ''), ')', ''), '+', '')"
+
+// GetOrdersByCustomer searches orders by customer contacts. Search strings without letters are
+// matched against normalized phones as well as raw links and addresses, profile links in any form
+// and "@handle" values are matched against canonical social handles and return all orders of
+// the resolved customer. Rows stored before these columns were introduced are matched by raw
+// "Phone" and "CustomerLink" values
 func (sqlite *SqliteDB) GetOrdersByCustomer(searchString string) ([]Data, error) {
-	query := fmt.Sprintf("SELECT * FROM %s ", config.DataTableName)
+	query := fmt.Sprintf("SELECT %s FROM %s ", dataColumns(), config.DataTableName)
 
 	if !config.LettersRegex.MatchString(searchString) {
-		var builder strings.Builder
-
-		for _, char := range searchString {
-			if unicode.IsDigit(char) {
-				if builder.Len() > 0 {
-					builder.WriteRune('%')
-				}
-				builder.WriteRune(char)
-			}
+		digits := fieldparser.PhoneDigits(searchString)
+		if digits == "" {
+			return nil, nil
+		}
+
+		condition, phoneArg := "PhoneNormalized LIKE ?", "%"+digits+"%"
+		if phone, ok := fieldparser.NormalizePhone(searchString); ok && phone != "" {
+			condition, phoneArg = "PhoneNormalized = ?", phone
 		}
 
-		searchString = builder.String()
+		// digits may also be a part of a profile link ("vk.com/id123456") or an address
+		raw := "%" + strings.TrimSpace(searchString) + "%"
+
+		query += fmt.Sprintf("WHERE %s OR (PhoneNormalized = '' AND %s LIKE ?) OR CustomerLink LIKE ? OR "+
+			"DeliveryAddress LIKE ? ORDER BY Date DESC, RowNumber ASC;", condition, rawPhoneDigits)
+		return executeQuery(sqlite, query, phoneArg, "%"+digits+"%", raw, raw)
+	}
+
+	if network, handle := fieldparser.ParseSocialLink(searchString, ""); handle != "" {
+		query += "WHERE " + withSameCustomer("(SocialNetwork = ? AND SocialHandle = ?) OR "+
+			"(SocialHandle = '' AND LOWER(CustomerLink) LIKE ?)") + " ORDER BY Date DESC, RowNumber ASC;"
+		args := []interface{}{network, handle, "%" + handle + "%"}
+		return executeQuery(sqlite, query, append(args, args...)...)
+	}
+
+	if strings.HasPrefix(searchString, "@") && !strings.Contains(searchString, " ") {
+		handle := strings.ToLower(searchString[1:])
+		query += "WHERE " + withSameCustomer("SocialHandle = ? OR "+
+			"(SocialHandle = '' AND LOWER(CustomerLink) LIKE ?)") + " ORDER BY Date DESC, RowNumber ASC;"
+		args := []interface{}{handle, "%" + handle + "%"}
+		return executeQuery(sqlite, query, append(args, args...)...)
 	}
 
 	query += fmt.Sprintf(
@@ -473,27 +633,142 @@ func (sqlite *SqliteDB) GetOrdersByCustomer(searchString string) ([]Data, error)
 	return executeQuery(sqlite, query)
 }
 
-func executeQuery(sqlite *SqliteDB, query string) ([]Data, error) {
-	rows, err := sqlite.DB.Query(query)
+// withSameCustomer extends condition to all rows of the customers found by it. Arguments of
+// condition must be passed twice
+func withSameCustomer(condition string) string {
+	return fmt.Sprintf("(%s) OR CustomerID IN (SELECT CustomerID FROM %s WHERE CustomerID != 0 AND (%s))",
+		condition, config.DataTableName, condition)
+}
+
+// GetOrdersBySearchWord returns all orders which Search field contains given word. It is used
+// to narrow down candidates before comparing inscriptions precisely
+func (sqlite *SqliteDB) GetOrdersBySearchWord(word string) ([]Data, error) {
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE Search LIKE ? ORDER BY Date DESC, RowNumber ASC;",
+		dataColumns(), config.DataTableName)
+
+	return executeQuery(sqlite, query, "%"+strings.ToUpper(word)+"%")
+}
+
+// GetOrdersByNotes returns rows which cell notes contain every word of searchString
+func (sqlite *SqliteDB) GetOrdersByNotes(searchString string) ([]Data, error) {
+	words := strings.Fields(strings.ToUpper(searchString))
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	conditions := make([]string, len(words))
+	args := make([]interface{}, len(words))
+	for i, word := range words {
+		conditions[i] = "NotesSearch LIKE ?"
+		args[i] = "%" + word + "%"
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY Date DESC, RowNumber ASC;",
+		dataColumns(), config.DataTableName, strings.Join(conditions, " AND "))
+
+	return executeQuery(sqlite, query, args...)
+}
+
+// GetOrdersByShipment searches orders by shipment identifiers. identifierType must be one of
+// config.ShipmentIdentifierFields keys, empty value (or "any") searches through all of them
+func (sqlite *SqliteDB) GetOrdersByShipment(searchString, identifierType string) ([]Data, error) {
+	var columns []string
+
+	if identifierType == "" || identifierType == config.AnyIdentifier {
+		for _, key := range config.ShipmentIdentifierTypes {
+			columns = append(columns, config.ShipmentIdentifierFields[key])
+		}
+	} else {
+		column, exists := config.ShipmentIdentifierFields[identifierType]
+		if !exists {
+			return nil, fmt.Errorf("%w %s", config.ErrUnknownIdentifier, identifierType)
+		}
+		columns = append(columns, column)
+	}
+
+	searchString = NormalizeIdentifier(searchString)
+	if searchString == "" {
+		return nil, nil
+	}
+
+	conditions := make([]string, 0, len(columns))
+	args := make([]interface{}, 0, len(columns))
+
+	for _, column := range columns {
+		conditions = append(conditions, fmt.Sprintf(
+			"UPPER(REPLACE(REPLACE(REPLACE(%s, ' ', ''), '-', ''), char(160), '')) LIKE ?", column))
+		args = append(args, "%"+searchString+"%")
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY Date DESC, RowNumber ASC;",
+		dataColumns(), config.DataTableName, strings.Join(conditions, " OR "))
+
+	return executeQuery(sqlite, query, args...)
+}
+
+// GetRow returns a stored row of a sheet
+func (sqlite *SqliteDB) GetRow(date string, rowNumber int) (Data, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE Date = ? AND RowNumber = ?;",
+		dataColumns(), config.DataTableName)
+
+	rows, err := executeQuery(sqlite, query, date, rowNumber)
+	if err != nil {
+		return Data{}, err
+	}
+	if len(rows) == 0 {
+		return Data{}, config.ErrNoRecordFound
+	}
+
+	return rows[0], nil
+}
+
+// NormalizeIdentifier strips spaces and dashes from shipment identifiers (Boxberry and pickup
+// numbers, PVZ codes, emails, post codes) and converts them to upper case
+func NormalizeIdentifier(identifier string) string {
+	identifier = strings.NewReplacer(" ", "", "-", "", "\u00A0", "").Replace(identifier)
+	return strings.ToUpper(strings.TrimSpace(identifier))
+}
+
+// dataColumns returns comma separated column names of Data table in the order of Data struct
+// fields, so that selected rows can be scanned by executeQuery
+func dataColumns() string {
+	t := reflect.TypeOf(Data{})
+	columns := make([]string, 0, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		columns = append(columns, t.Field(i).Name)
+	}
+
+	return strings.Join(columns, ", ")
+}
+
+func executeQuery(sqlite *SqliteDB, query string, args ...interface{}) ([]Data, error) {
+	rows, err := sqlite.DB.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
+
+	return scanData(rows)
+}
+
+// scanData scans rows selected with dataColumns into Data structs and closes them
+func scanData(rows *sql.Rows) ([]Data, error) {
 	defer rows.Close()
 
 	var entries []Data
+	var err error
 
 	for rows.Next() {
 		var entry Data
-		err = rows.Scan(&entry.Date, &entry.RowNumber, &entry.Search, &entry.IsMerged,
-			&entry.OrderLink, &entry.Payment, &entry.PVZ, &entry.Email, &entry.Inscription,
-			&entry.Details, &entry.Texture, &entry.Pendant, &entry.Ring, &entry.ForNotes,
-			&entry.Socials, &entry.FullName, &entry.InscriptionBracelet, &entry.Description,
-			&entry.PostCode, &entry.CustomerLink, &entry.TimeTo, &entry.EdgeLower,
-			&entry.DeliveryCost, &entry.Phone, &entry.Earrings, &entry.City, &entry.TimeFrom,
-			&entry.DeliveryType, &entry.Notes, &entry.BoxberryNumber, &entry.EdgeUpper, &entry.Type,
-			&entry.Extras, &entry.DeliveryAddress, &entry.ForConfirmation, &entry.Symbol,
-			&entry.Subtype, &entry.Sum, &entry.PickupNumber)
-		if err != nil {
+
+		v := reflect.ValueOf(&entry).Elem()
+		fieldPointers := make([]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			fieldPointers[i] = v.Field(i).Addr().Interface()
+		}
+
+		if err = rows.Scan(fieldPointers...); err != nil {
 			return nil, err
 		}
 
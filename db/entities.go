@@ -0,0 +1,198 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+)
+
+func (sqlite *SqliteDB) initInscriptionEntitiesTable() error {
+	createTablesSQL := []string{
+		fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS %s (
+				Date TEXT,
+				RowNumber INTEGER,
+				Type TEXT,
+				Entity TEXT,
+				Value TEXT,
+				Year INTEGER
+			);
+		`, config.InscriptionEntitiesTable),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_date ON %s (Date);",
+			strings.ToLower(config.InscriptionEntitiesTable), config.InscriptionEntitiesTable),
+	}
+
+	for _, createTableSQL := range createTablesSQL {
+		if _, err := sqlite.DB.Exec(createTableSQL); err != nil {
+			return fmt.Errorf("failed to create inscription entities table: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ReplaceInscriptionEntitiesByDateWithTx rewrites entities found in inscriptions of a sheet
+func (sqlite *SqliteDB) ReplaceInscriptionEntitiesByDateWithTx(
+	tx *sql.Tx, date string, entities []InscriptionEntity,
+) error {
+
+	deleteSQL := fmt.Sprintf("DELETE FROM %s WHERE Date = ?;", config.InscriptionEntitiesTable)
+	if _, err := tx.Exec(deleteSQL, date); err != nil {
+		return fmt.Errorf("failed to delete inscription entities: %w", err)
+	}
+
+	insertSQL := fmt.Sprintf(
+		"INSERT INTO %s (Date, RowNumber, Type, Entity, Value, Year) VALUES (?, ?, ?, ?, ?, ?)",
+		config.InscriptionEntitiesTable)
+
+	statement, err := tx.Prepare(insertSQL)
+	if err != nil {
+		return fmt.Errorf("failed to prepare SQL statement: %w", err)
+	}
+	defer statement.Close()
+
+	for _, entity := range entities {
+		_, err = statement.Exec(date, entity.RowNumber, entity.Type, entity.Entity, entity.Value, entity.Year)
+		if err != nil {
+			return fmt.Errorf("failed to execute SQL statement: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetEntityValueCounts returns numbers of rows per product type, entity and value of batches
+// within optional inclusive date range. Dates are counted by year. Empty productType means
+// all types
+func (sqlite *SqliteDB) GetEntityValueCounts(from, to, productType string) ([]EntityValueCount, error) {
+	condition, args := dateRangeCondition("Date", from, to)
+	if productType != "" {
+		condition += " AND Type = ?"
+		args = append(args, productType)
+	}
+
+	query := fmt.Sprintf(
+		`SELECT Type, Entity, CASE WHEN Year != 0 THEN CAST(Year AS TEXT) ELSE Value END AS EntityValue,
+			COUNT(DISTINCT Date || '-' || RowNumber)
+		FROM %s
+		WHERE %s AND %s
+		GROUP BY Type, Entity, EntityValue
+		ORDER BY Type ASC, Entity ASC, COUNT(DISTINCT Date || '-' || RowNumber) DESC, EntityValue ASC;`,
+		config.InscriptionEntitiesTable, condition, batchDateCondition)
+
+	rows, err := sqlite.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []EntityValueCount
+
+	for rows.Next() {
+		var count EntityValueCount
+		if err = rows.Scan(&count.Type, &count.Entity, &count.Value, &count.Rows); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// GetEntityRowCounts returns numbers of rows per product type and entity of batches within
+// optional inclusive date range
+func (sqlite *SqliteDB) GetEntityRowCounts(from, to, productType string) (map[string]map[string]int, error) {
+	condition, args := dateRangeCondition("Date", from, to)
+	if productType != "" {
+		condition += " AND Type = ?"
+		args = append(args, productType)
+	}
+
+	query := fmt.Sprintf(
+		`SELECT Type, Entity, COUNT(DISTINCT Date || '-' || RowNumber) FROM %s
+		WHERE %s AND %s
+		GROUP BY Type, Entity;`,
+		config.InscriptionEntitiesTable, condition, batchDateCondition)
+
+	rows, err := sqlite.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]map[string]int)
+
+	for rows.Next() {
+		var productType, entity string
+		var count int
+		if err = rows.Scan(&productType, &entity, &count); err != nil {
+			return nil, err
+		}
+		if counts[productType] == nil {
+			counts[productType] = make(map[string]int)
+		}
+		counts[productType][entity] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// GetInscriptionTypeCounts returns numbers of rows with inscriptions counted in essentials per
+// product type of batches within optional inclusive date range
+func (sqlite *SqliteDB) GetInscriptionTypeCounts(from, to, productType string) (map[string]int, error) {
+	columns, args, err := inscriptionColumns(config.AppSettings.EssentialsRules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile essentials rules: %w", err)
+	}
+
+	condition, conditionArgs := dateRangeCondition("Date", from, to)
+	args = append(args, conditionArgs...)
+	if productType != "" {
+		condition += " AND Type = ?"
+		args = append(args, productType)
+	}
+
+	nonEmpty := make([]string, len(columns))
+	for i, column := range columns {
+		nonEmpty[i] = fmt.Sprintf("(%s) != ''", column)
+	}
+
+	query := fmt.Sprintf(
+		"SELECT Type, SUM(%s) FROM %s WHERE %s AND %s GROUP BY Type;",
+		strings.Join(nonEmpty, " OR "), config.DataTableName, condition, batchDateCondition)
+
+	rows, err := sqlite.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+
+	for rows.Next() {
+		var productType string
+		var count int
+		if err = rows.Scan(&productType, &count); err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			counts[productType] = count
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+)
+
+// batchDateCondition skips "НАЛИЧИЕ" and "Срочные заказы" sheets which are stored with zero month
+const batchDateCondition = "substr(Date, 6, 2) != '00'"
+
+func (sqlite *SqliteDB) initEssentialsTables() error {
+	createTablesSQL := []string{
+		fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS %s (
+				Word TEXT,
+				Kind TEXT,
+				Date TEXT,
+				Count INTEGER,
+				Orders INTEGER,
+				PRIMARY KEY (Kind, Word, Date)
+			);
+		`, config.EssentialsTableName),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_date ON %s (Date);",
+			strings.ToLower(config.EssentialsTableName), config.EssentialsTableName),
+		fmt.Sprintf(`
+			CREATE TABLE IF NOT EXISTS %s (
+				Date TEXT PRIMARY KEY,
+				Orders INTEGER
+			);
+		`, config.EssentialsTotalsTableName),
+	}
+
+	for _, createTableSQL := range createTablesSQL {
+		if _, err := sqlite.DB.Exec(createTableSQL); err != nil {
+			return fmt.Errorf("failed to create essentials tables: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// ReplaceEssentialsByDateWithTx rewrites word and phrase counts of a sheet together with the
+// number of orders with inscriptions in it
+func (sqlite *SqliteDB) ReplaceEssentialsByDateWithTx(
+	tx *sql.Tx, date string, counts []EssentialCount, orders int,
+) error {
+
+	deleteSQL := fmt.Sprintf("DELETE FROM %s WHERE Date = ?;", config.EssentialsTableName)
+	if _, err := tx.Exec(deleteSQL, date); err != nil {
+		return fmt.Errorf("failed to delete essentials: %w", err)
+	}
+
+	insertSQL := fmt.Sprintf("INSERT INTO %s (Word, Kind, Date, Count, Orders) VALUES (?, ?, ?, ?, ?)",
+		config.EssentialsTableName)
+
+	statement, err := tx.Prepare(insertSQL)
+	if err != nil {
+		return fmt.Errorf("failed to prepare SQL statement: %w", err)
+	}
+	defer statement.Close()
+
+	for _, count := range counts {
+		_, err = statement.Exec(count.Word, count.Kind, date, count.Count, count.Orders)
+		if err != nil {
+			return fmt.Errorf("failed to execute SQL statement: %w", err)
+		}
+	}
+
+	totalsSQL := fmt.Sprintf("INSERT OR REPLACE INTO %s (Date, Orders) VALUES (?, ?)",
+		config.EssentialsTotalsTableName)
+
+	if _, err = tx.Exec(totalsSQL, date, orders); err != nil {
+		return fmt.Errorf("failed to update essentials totals: %w", err)
+	}
+
+	return nil
+}
+
+// GetEssentialStats returns limit most frequent words, phrases or lemmas (kind) of batches within
+// optional inclusive date range
+func (sqlite *SqliteDB) GetEssentialStats(from, to, kind string, limit int) ([]EssentialStats, error) {
+	condition, args := dateRangeCondition("e.Date", from, to)
+	args = append([]interface{}{kind}, args...)
+	args = append(args, limit)
+
+	query := fmt.Sprintf(
+		`SELECT e.Word, SUM(e.Count), SUM(e.Orders), s.FirstSeen, s.LastSeen
+		FROM %s e
+		JOIN (
+			SELECT Kind, Word, MIN(Date) AS FirstSeen, MAX(Date) AS LastSeen
+			FROM %s WHERE %s GROUP BY Kind, Word
+		) s ON s.Kind = e.Kind AND s.Word = e.Word
+		WHERE e.Kind = ? AND %s AND substr(e.Date, 6, 2) != '00'
+		GROUP BY e.Word
+		ORDER BY SUM(e.Count) DESC, e.Word ASC
+		LIMIT ?;`,
+		config.EssentialsTableName, config.EssentialsTableName, batchDateCondition, condition)
+
+	rows, err := sqlite.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []EssentialStats{}
+
+	for rows.Next() {
+		var wordStats EssentialStats
+		err = rows.Scan(&wordStats.Word, &wordStats.Count, &wordStats.Orders,
+			&wordStats.FirstSeen, &wordStats.LastSeen)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, wordStats)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// GetEssentialsTotalOrders returns number of orders with inscriptions in batches within
+// optional inclusive date range
+func (sqlite *SqliteDB) GetEssentialsTotalOrders(from, to string) (int, error) {
+	condition, args := dateRangeCondition("Date", from, to)
+
+	query := fmt.Sprintf("SELECT COALESCE(SUM(Orders), 0) FROM %s WHERE %s AND %s;",
+		config.EssentialsTotalsTableName, condition, batchDateCondition)
+
+	var total int
+	if err := sqlite.DB.QueryRow(query, args...).Scan(&total); err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
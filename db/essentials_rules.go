@@ -0,0 +1,262 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+)
+
+// inscriptionFields are sheet fields holding engraved inscriptions counted in essentials
+var inscriptionFields = []string{
+	"Надпись", "Нижний торец", "Верхний торец", "Подвеска", "Кольцо", "Браслет надпись",
+}
+
+// RuleExclusion is a number of rows of a "Тип" and "Вид" which inscriptions are excluded from
+// essentials by a rule
+type RuleExclusion struct {
+	Type    string
+	Subtype string
+	Rows    int
+}
+
+// fieldColumn returns column name of a sheet field
+func fieldColumn(fieldname string) (string, bool) {
+	t := reflect.TypeOf(Data{})
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("fieldname") == fieldname {
+			return t.Field(i).Name, true
+		}
+	}
+	return "", false
+}
+
+func validateEssentialsRules(rules []config.EssentialsRule) error {
+	for _, rule := range rules {
+		if rule.Action != config.EssentialsRuleInclude && rule.Action != config.EssentialsRuleExclude {
+			return fmt.Errorf("essentials rule %q has unknown action %q", rule.Name, rule.Action)
+		}
+		for _, field := range rule.Fields {
+			if !slices.Contains(inscriptionFields, field) {
+				return fmt.Errorf("essentials rule %q has unknown inscription field %q", rule.Name, field)
+			}
+		}
+	}
+	return nil
+}
+
+// ruleApplies reports whether a rule is applied to an inscription field
+func ruleApplies(rule config.EssentialsRule, field string) bool {
+	return len(rule.Fields) == 0 || slices.Contains(rule.Fields, field)
+}
+
+// ruleCondition builds SQL condition matching rows of a rule
+func ruleCondition(rule config.EssentialsRule) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if len(rule.Types) > 0 {
+		placeholders := make([]string, len(rule.Types))
+		for i, ruleType := range rule.Types {
+			placeholders[i] = "?"
+			args = append(args, ruleType)
+		}
+		conditions = append(conditions, fmt.Sprintf("Type IN (%s)", strings.Join(placeholders, ", ")))
+	}
+
+	if len(rule.SubtypePatterns) > 0 {
+		patterns := make([]string, len(rule.SubtypePatterns))
+		for i, pattern := range rule.SubtypePatterns {
+			patterns[i] = "Subtype LIKE ?"
+			args = append(args, pattern)
+		}
+		conditions = append(conditions, "("+strings.Join(patterns, " OR ")+")")
+	}
+
+	if len(conditions) == 0 {
+		return "1 = 1", nil
+	}
+
+	return "(" + strings.Join(conditions, " AND ") + ")", args
+}
+
+// rulesCondition builds SQL condition matching rows of any rule with a given action applied
+// to an inscription field, empty if there are no such rules
+func rulesCondition(rules []config.EssentialsRule, action, field string) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	for _, rule := range rules {
+		if rule.Action != action || !ruleApplies(rule, field) {
+			continue
+		}
+		condition, conditionArgs := ruleCondition(rule)
+		conditions = append(conditions, condition)
+		args = append(args, conditionArgs...)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// inscriptionColumns compiles essentials rules into select expressions of every inscription
+// field, values of excluded fields are selected as empty strings
+func inscriptionColumns(rules []config.EssentialsRule) ([]string, []interface{}, error) {
+	if err := validateEssentialsRules(rules); err != nil {
+		return nil, nil, err
+	}
+
+	var columns []string
+	var args []interface{}
+
+	for _, field := range inscriptionFields {
+		column, _ := fieldColumn(field)
+
+		excluded, excludedArgs := rulesCondition(rules, config.EssentialsRuleExclude, field)
+		if excluded == "" {
+			columns = append(columns, column)
+			continue
+		}
+
+		included, includedArgs := rulesCondition(rules, config.EssentialsRuleInclude, field)
+		if included == "" {
+			included = "0"
+		}
+
+		columns = append(columns,
+			fmt.Sprintf("CASE WHEN %s AND NOT %s THEN '' ELSE %s END", excluded, included, column))
+		args = append(args, excludedArgs...)
+		args = append(args, includedArgs...)
+	}
+
+	return columns, args, nil
+}
+
+// GetEssentialsRuleExclusions returns numbers of rows per "Тип" and "Вид" which non-empty
+// inscriptions are excluded by the rule with a given index and not kept by include rules
+func (sqlite *SqliteDB) GetEssentialsRuleExclusions(
+	rules []config.EssentialsRule, index int,
+) ([]RuleExclusion, error) {
+
+	if err := validateEssentialsRules(rules); err != nil {
+		return nil, err
+	}
+
+	rule := rules[index]
+	if rule.Action != config.EssentialsRuleExclude {
+		return nil, nil
+	}
+
+	condition, args := ruleCondition(rule)
+
+	var fieldConditions []string
+	for _, field := range inscriptionFields {
+		if !ruleApplies(rule, field) {
+			continue
+		}
+		column, _ := fieldColumn(field)
+
+		fieldCondition := fmt.Sprintf("%s != ''", column)
+		included, includedArgs := rulesCondition(rules, config.EssentialsRuleInclude, field)
+		if included != "" {
+			fieldCondition += " AND NOT " + included
+			args = append(args, includedArgs...)
+		}
+		fieldConditions = append(fieldConditions, "("+fieldCondition+")")
+	}
+
+	query := fmt.Sprintf(
+		`SELECT Type, Subtype, COUNT(*) FROM %s
+		WHERE %s AND (%s)
+		GROUP BY Type, Subtype
+		ORDER BY COUNT(*) DESC, Type ASC, Subtype ASC;`,
+		config.DataTableName, condition, strings.Join(fieldConditions, " OR "))
+
+	rows, err := sqlite.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var exclusions []RuleExclusion
+
+	for rows.Next() {
+		var exclusion RuleExclusion
+		if err = rows.Scan(&exclusion.Type, &exclusion.Subtype, &exclusion.Rows); err != nil {
+			return nil, err
+		}
+		exclusions = append(exclusions, exclusion)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return exclusions, nil
+}
+
+// GetInscriptionFields returns inscription fields not excluded by essentials rules of rows
+// with inscriptions of batches within optional inclusive date range. Empty productType means
+// all types
+func (sqlite *SqliteDB) GetInscriptionFields(from, to, productType string) ([]InscriptionFields, error) {
+	columns, args, err := inscriptionColumns(config.AppSettings.EssentialsRules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile essentials rules: %w", err)
+	}
+
+	condition, conditionArgs := dateRangeCondition("Date", from, to)
+	args = append(args, conditionArgs...)
+	if productType != "" {
+		condition += " AND Type = ?"
+		args = append(args, productType)
+	}
+
+	query := fmt.Sprintf("SELECT Type, Subtype, %s FROM %s WHERE %s AND %s;",
+		strings.Join(columns, ", "), config.DataTableName, condition, batchDateCondition)
+
+	rows, err := sqlite.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var inscriptionRows []InscriptionFields
+
+	for rows.Next() {
+		row := InscriptionFields{Fields: make(map[string]string)}
+		values := make([]string, len(inscriptionFields))
+		dest := []interface{}{&row.Type, &row.Subtype}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		for i, value := range values {
+			if value != "" {
+				row.Fields[inscriptionFields[i]] = value
+			}
+		}
+
+		if len(row.Fields) > 0 {
+			inscriptionRows = append(inscriptionRows, row)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return inscriptionRows, nil
+}
+
+// InscriptionFieldnames returns sheet names of inscription fields
+func InscriptionFieldnames() []string {
+	return append([]string(nil), inscriptionFields...)
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+)
+
+func (sqlite *SqliteDB) initIngestionStatsTable() error {
+	createTableSQL := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			Date TEXT PRIMARY KEY,
+			HeaderRow INTEGER,
+			HeaderRows INTEGER,
+			MatchedColumns INTEGER,
+			UnknownColumns TEXT,
+			RowsStored INTEGER,
+			OrdersStored INTEGER
+		);
+	`, config.IngestionStatsTableName)
+
+	if _, err := sqlite.DB.Exec(createTableSQL); err != nil {
+		return fmt.Errorf("failed to create table %s: %w", config.IngestionStatsTableName, err)
+	}
+
+	return nil
+}
+
+func (sqlite *SqliteDB) ReplaceIngestionStatsWithTx(tx *sql.Tx, stats IngestionStats) error {
+	insertSQL := fmt.Sprintf(
+		`INSERT OR REPLACE INTO %s
+		(Date, HeaderRow, HeaderRows, MatchedColumns, UnknownColumns, RowsStored, OrdersStored)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		config.IngestionStatsTableName)
+
+	_, err := tx.Exec(insertSQL, stats.Date, stats.HeaderRow, stats.HeaderRows,
+		stats.MatchedColumns, stats.UnknownColumns, stats.RowsStored, stats.OrdersStored)
+	if err != nil {
+		return fmt.Errorf("failed to insert ingestion stats: %w", err)
+	}
+
+	return nil
+}
+
+// GetIngestionStats returns parse statistics of sheets within optional inclusive date range
+func (sqlite *SqliteDB) GetIngestionStats(from, to string) ([]IngestionStats, error) {
+	condition, args := dateRangeCondition("Date", from, to)
+
+	query := fmt.Sprintf(
+		`SELECT Date, HeaderRow, HeaderRows, MatchedColumns, UnknownColumns, RowsStored, OrdersStored
+		FROM %s WHERE %s ORDER BY Date DESC;`,
+		config.IngestionStatsTableName, condition)
+
+	rows, err := sqlite.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []IngestionStats{}
+
+	for rows.Next() {
+		var sheetStats IngestionStats
+		err = rows.Scan(&sheetStats.Date, &sheetStats.HeaderRow, &sheetStats.HeaderRows,
+			&sheetStats.MatchedColumns, &sheetStats.UnknownColumns, &sheetStats.RowsStored,
+			&sheetStats.OrdersStored)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, sheetStats)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
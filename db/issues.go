@@ -0,0 +1,154 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+)
+
+func (sqlite *SqliteDB) initIssuesTable() error {
+	createTableSQL := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			Date TEXT,
+			RowNumber INTEGER,
+			Rule TEXT,
+			Severity TEXT,
+			Message TEXT,
+			PRIMARY KEY (Date, RowNumber, Rule)
+		);
+	`, config.IssuesTableName)
+
+	if _, err := sqlite.DB.Exec(createTableSQL); err != nil {
+		return fmt.Errorf("failed to create table %s: %w", config.IssuesTableName, err)
+	}
+
+	return nil
+}
+
+// GetOrdersByDate fetches all stored rows of a single sheet
+func (sqlite *SqliteDB) GetOrdersByDate(date string) ([]Data, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE Date = ? ORDER BY RowNumber ASC;",
+		dataColumns(), config.DataTableName)
+
+	return executeQuery(sqlite, query, date)
+}
+
+// ReplaceIssuesByDateWithTx removes previously found issues of a sheet and stores new ones
+func (sqlite *SqliteDB) ReplaceIssuesByDateWithTx(tx *sql.Tx, date string, issues []Issue) error {
+	deleteSQL := fmt.Sprintf("DELETE FROM %s WHERE Date = ?;", config.IssuesTableName)
+
+	if _, err := tx.Exec(deleteSQL, date); err != nil {
+		return fmt.Errorf("failed to delete issues: %w", err)
+	}
+
+	insertSQL := fmt.Sprintf(
+		"INSERT OR REPLACE INTO %s (Date, RowNumber, Rule, Severity, Message) VALUES (?, ?, ?, ?, ?)",
+		config.IssuesTableName)
+
+	statement, err := tx.Prepare(insertSQL)
+	if err != nil {
+		return fmt.Errorf("failed to prepare SQL statement: %w", err)
+	}
+	defer statement.Close()
+
+	for _, issue := range issues {
+		_, err = statement.Exec(issue.Date, issue.RowNumber, issue.Rule, issue.Severity, issue.Message)
+		if err != nil {
+			return fmt.Errorf("failed to execute SQL statement: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetIssues fetches issues filtered by optional date range, severity and rule
+func (sqlite *SqliteDB) GetIssues(from, to, severity, rule string) ([]Issue, error) {
+	condition, args := dateRangeCondition("i.Date", from, to)
+
+	if severity != "" {
+		condition += " AND i.Severity = ?"
+		args = append(args, severity)
+	}
+	if rule != "" {
+		condition += " AND i.Rule = ?"
+		args = append(args, rule)
+	}
+
+	query := fmt.Sprintf(
+		`SELECT i.Date, i.RowNumber, i.Rule, i.Severity, i.Message, COALESCE(d.OrderLink, '')
+		FROM %s i
+		LEFT JOIN %s d ON d.Date = i.Date AND d.RowNumber = i.RowNumber
+		WHERE %s
+		ORDER BY i.Date DESC, i.RowNumber ASC, i.Rule ASC;`,
+		config.IssuesTableName, config.DataTableName, condition)
+
+	rows, err := sqlite.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	issues := []Issue{}
+
+	for rows.Next() {
+		var issue Issue
+		err = rows.Scan(&issue.Date, &issue.RowNumber, &issue.Rule, &issue.Severity,
+			&issue.Message, &issue.OrderLink)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, issue)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return issues, nil
+}
+
+// GetIssueCounts counts issues by date and rule for given dates
+func (sqlite *SqliteDB) GetIssueCounts(dates []string) ([]IssueCount, error) {
+	if len(dates) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(dates))
+	args := make([]interface{}, len(dates))
+	for i, date := range dates {
+		placeholders[i] = "?"
+		args[i] = date
+	}
+
+	query := fmt.Sprintf(
+		`SELECT Date, Rule, Severity, COUNT(*)
+		FROM %s
+		WHERE Date IN (%s)
+		GROUP BY Date, Rule, Severity
+		ORDER BY Date ASC, Severity ASC, Rule ASC;`,
+		config.IssuesTableName, strings.Join(placeholders, ", "))
+
+	rows, err := sqlite.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []IssueCount
+
+	for rows.Next() {
+		var count IssueCount
+		if err = rows.Scan(&count.Date, &count.Rule, &count.Severity, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
@@ -2,6 +2,15 @@ package db
 
 var primaryKeys = []string{"Date", "RowNumber"}
 
+// dateColumns lists columns of "Dates" table added by later versions, used to migrate old DBs
+type dateColumns struct {
+	Words   string
+	Phrases string
+	Lemmas  string
+}
+
+var indexedColumns = []string{"CustomerID", "PhoneNormalized", "SocialHandle", "OrderID"}
+
 type Data struct {
 	Date      string
 	RowNumber int
@@ -43,4 +52,204 @@ type Data struct {
 	Subtype             string `fieldname:"Вид"`
 	Sum                 int    `fieldname:"Сумма"`
 	PickupNumber        string `fieldname:"Номер самовывоза"`
+
+	CustomerID      int
+	PhoneNormalized string
+	PhoneInvalid    bool
+	SocialNetwork   string
+	SocialHandle    string
+
+	AddressRegion     string
+	AddressCity       string
+	AddressStreet     string
+	AddressHouse      string
+	AddressApartment  string
+	AddressPostCode   string
+	AddressConfidence int
+	PostCodeMismatch  bool
+
+	SumKopecks          int
+	SumRaw              string
+	SumStatus           string
+	DeliveryCostKopecks int
+	DeliveryCostStatus  string
+
+	PaymentStatus string
+	PaidKopecks   int
+
+	DeliveryMethod string
+	TimeFromParsed string
+	TimeToParsed   string
+
+	OrderID      string
+	MergedFields string
+
+	RowColor string
+	Status   string
+
+	CellNotes   string
+	NotesSearch string
+
+	// StatusCaptured is false for rows stored before row colours were mapped to statuses
+	StatusCaptured bool
+}
+
+// Order is a customer's order in a batch. Rows of a sheet sharing merged customer cells are
+// items of the same order, ID is built from batch date and the first row ("2024.04.20-15").
+// Contacts and delivery are taken from the first item, sums are totals of all items
+type Order struct {
+	ID                  string
+	Date                string
+	FirstRow            int
+	ItemsCount          int
+	CustomerID          int
+	FullName            string
+	Phone               string
+	PhoneNormalized     string
+	CustomerLink        string
+	Socials             string
+	Email               string
+	DeliveryAddress     string
+	DeliveryType        string
+	DeliveryMethod      string
+	PickupNumber        string
+	TimeFrom            string
+	TimeTo              string
+	TimeFromParsed      string
+	TimeToParsed        string
+	Payment             string
+	PaymentStatus       string
+	SumKopecks          int
+	PaidKopecks         int
+	DeliveryCostKopecks int
+	OrderLink           string
+	Status              string
+}
+
+type Customer struct {
+	ID           int
+	FullName     string
+	Phone        string
+	CustomerLink string
+	Socials      string
+	Email        string
+}
+
+// CustomerOverride forces two customer keys (eg "phone:+79161234567" and "link:vk/id1")
+// to be resolved as the same customer (merge) or as different customers (split)
+type CustomerOverride struct {
+	Kind string
+	KeyA string
+	KeyB string
+}
+
+type OrderCustomer struct {
+	Date       string
+	RowNumber  int
+	CustomerID int
+}
+
+// Issue is a data quality problem found by a rule in a stored order. OrderLink is not stored
+// in Issues table and is filled from Data table when issues are fetched
+type Issue struct {
+	Date      string
+	RowNumber int
+	Rule      string
+	Severity  string
+	Message   string
+	OrderLink string
+}
+
+type DeliveryCount struct {
+	Date           string
+	DeliveryMethod string
+	Count          int
+}
+
+type IssueCount struct {
+	Date     string
+	Rule     string
+	Severity string
+	Count    int
+}
+
+// IngestionStats describes how a sheet was parsed during the last store. HeaderRow is
+// a 1-based sheet row number
+type IngestionStats struct {
+	Date           string
+	HeaderRow      int
+	HeaderRows     int
+	MatchedColumns int
+	UnknownColumns string
+	RowsStored     int
+	OrdersStored   int
+}
+
+// StatusChange is a change of row status (background colour) noticed while storing a sheet.
+// PreviousStatus is empty for rows which were stored for the first time
+type StatusChange struct {
+	Date           string
+	RowNumber      int
+	OrderID        string
+	PreviousStatus string
+	Status         string
+	ChangedAt      string
+	OrderLink      string
+}
+
+type InscriptionRow struct {
+	RowNumber    int
+	OrderID      string
+	Type         string
+	Inscriptions []string
+}
+
+// EssentialCount is a word or phrase count of a single sheet. Orders is the number of orders
+// which inscriptions contain it
+type EssentialCount struct {
+	Word   string
+	Kind   string
+	Date   string
+	Count  int
+	Orders int
+}
+
+// EssentialStats is a word or phrase count over a date range. FirstSeen and LastSeen are
+// batch dates of its first and last use over all stored sheets
+type EssentialStats struct {
+	Word      string
+	Count     int
+	Orders    int
+	Share     float64
+	FirstSeen string
+	LastSeen  string
+	// Forms are words normalized to a lemma, filled for lemma statistics only
+	Forms []string
+}
+
+// InscriptionEntity is an entity found in inscriptions of a row. Value holds the normalized
+// entity (date as "2015.06.12", decimal coordinates, arabic value of a roman numeral), Year is
+// the year of an engraved date
+type InscriptionEntity struct {
+	Date      string
+	RowNumber int
+	Type      string
+	Entity    string
+	Value     string
+	Year      int
+}
+
+// EntityValueCount is a number of rows of a product type with an entity value
+type EntityValueCount struct {
+	Type   string
+	Entity string
+	Value  string
+	Rows   int
+}
+
+// InscriptionFields holds non-empty inscription fields of a row by sheet field names
+type InscriptionFields struct {
+	Type    string
+	Subtype string
+	Fields  map[string]string
 }
@@ -0,0 +1,162 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+)
+
+var orderIndexedColumns = []string{"Date", "CustomerID"}
+
+func (sqlite *SqliteDB) initOrdersTable() error {
+	t := reflect.TypeOf(Order{})
+	columns := make([]string, 0, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		column := fmt.Sprintf("%s %s", field.Name, getSQLType(field.Type.Kind()))
+		if field.Name == "ID" {
+			column += " PRIMARY KEY"
+		}
+		columns = append(columns, column)
+	}
+
+	createTableSQL := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s);",
+		config.OrdersTableName, strings.Join(columns, ", "))
+
+	if _, err := sqlite.DB.Exec(createTableSQL); err != nil {
+		return fmt.Errorf("failed to create table %s: %w", config.OrdersTableName, err)
+	}
+
+	if err := sqlite.addMissingColumns(config.OrdersTableName, t); err != nil {
+		return err
+	}
+
+	for _, column := range orderIndexedColumns {
+		createIndexSQL := fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_%s ON %s (%s);",
+			strings.ToLower(config.OrdersTableName), strings.ToLower(column),
+			config.OrdersTableName, column)
+
+		if _, err := sqlite.DB.Exec(createIndexSQL); err != nil {
+			return fmt.Errorf("failed to create index on %s: %w", column, err)
+		}
+	}
+
+	return nil
+}
+
+// ReplaceOrdersByDateWithTx removes previously stored orders of a sheet and stores new ones
+func (sqlite *SqliteDB) ReplaceOrdersByDateWithTx(tx *sql.Tx, date string, orders []*Order) error {
+	deleteSQL := fmt.Sprintf("DELETE FROM %s WHERE Date = ?;", config.OrdersTableName)
+	if _, err := tx.Exec(deleteSQL, date); err != nil {
+		return fmt.Errorf("failed to delete orders: %w", err)
+	}
+
+	if len(orders) == 0 {
+		return nil
+	}
+
+	t := reflect.TypeOf(Order{})
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", t.NumField()), ",")
+
+	insertSQL := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		config.OrdersTableName, orderColumns(), placeholders)
+
+	statement, err := tx.Prepare(insertSQL)
+	if err != nil {
+		return fmt.Errorf("failed to prepare SQL statement: %w", err)
+	}
+	defer statement.Close()
+
+	for _, order := range orders {
+		v := reflect.ValueOf(order).Elem()
+		values := make([]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			values[i] = v.Field(i).Interface()
+		}
+
+		if _, err = statement.Exec(values...); err != nil {
+			return fmt.Errorf("failed to execute SQL statement: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// UpdateOrderIDsWithTx sets OrderID of already stored rows, used to split rows stored by
+// previous versions into orders without re-fetching sheets
+func (sqlite *SqliteDB) UpdateOrderIDsWithTx(tx *sql.Tx, items []*Data) error {
+	updateSQL := fmt.Sprintf("UPDATE %s SET OrderID = ? WHERE Date = ? AND RowNumber = ?",
+		config.DataTableName)
+
+	statement, err := tx.Prepare(updateSQL)
+	if err != nil {
+		return fmt.Errorf("failed to prepare SQL statement: %w", err)
+	}
+	defer statement.Close()
+
+	for _, item := range items {
+		if _, err = statement.Exec(item.OrderID, item.Date, item.RowNumber); err != nil {
+			return fmt.Errorf("failed to update data: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetCustomerOrders returns orders of a resolved customer, most recent first
+func (sqlite *SqliteDB) GetCustomerOrders(customerID int) ([]Order, error) {
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE CustomerID = ? ORDER BY Date DESC, FirstRow ASC;",
+		orderColumns(), config.OrdersTableName)
+
+	return executeOrdersQuery(sqlite, query, customerID)
+}
+
+// orderColumns returns comma separated column names of Orders table in the order of Order
+// struct fields, so that selected rows can be scanned by executeOrdersQuery
+func orderColumns() string {
+	t := reflect.TypeOf(Order{})
+	columns := make([]string, 0, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		columns = append(columns, t.Field(i).Name)
+	}
+
+	return strings.Join(columns, ", ")
+}
+
+func executeOrdersQuery(sqlite *SqliteDB, query string, args ...interface{}) ([]Order, error) {
+	rows, err := sqlite.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []Order
+
+	for rows.Next() {
+		var order Order
+
+		v := reflect.ValueOf(&order).Elem()
+		fieldPointers := make([]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			fieldPointers[i] = v.Field(i).Addr().Interface()
+		}
+
+		if err = rows.Scan(fieldPointers...); err != nil {
+			return nil, err
+		}
+
+		orders = append(orders, order)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return orders, nil
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/fieldparser"
+)
+
+// dateRangeCondition builds SQL condition on a date column for optional inclusive date range
+// (dates are formatted as "2024.04.20")
+func dateRangeCondition(column, from, to string) (string, []interface{}) {
+	condition := "1 = 1"
+	var args []interface{}
+
+	if from != "" {
+		condition += fmt.Sprintf(" AND %s >= ?", column)
+		args = append(args, from)
+	}
+	if to != "" {
+		condition += fmt.Sprintf(" AND %s <= ?", column)
+		args = append(args, to)
+	}
+
+	return condition, args
+}
+
+// statusCondition adds optional row status filter to a condition
+func statusCondition(condition string, args []interface{}, status string) (string, []interface{}) {
+	if status == "" {
+		return condition, args
+	}
+	return condition + " AND Status = ?", append(args, status)
+}
+
+// GetAddressIssues returns orders with a delivery address which post code doesn't match
+// "Индекс" column or which was parsed with low confidence. Rows stored before addresses were
+// parsed have no parsed parts and are skipped
+func (sqlite *SqliteDB) GetAddressIssues(from, to, status string) ([]Data, error) {
+	condition, args := dateRangeCondition("Date", from, to)
+	condition, args = statusCondition(condition, args, status)
+
+	query := fmt.Sprintf(
+		`SELECT %s FROM %s
+		WHERE %s AND DeliveryAddress != '' AND (PostCodeMismatch = 1 OR AddressConfidence < ?) AND
+		(AddressRegion != '' OR AddressCity != '' OR AddressStreet != '' OR AddressHouse != '' OR
+		AddressPostCode != '')
+		ORDER BY Date DESC, RowNumber ASC;`,
+		dataColumns(), config.DataTableName, condition)
+
+	args = append(args, config.AddressLowConfidence)
+
+	return executeQuery(sqlite, query, args...)
+}
+
+// GetRecentBatchDates returns dates of the most recent regular batch sheets, skipping
+// "НАЛИЧИЕ" and "Срочные заказы" sheets which are stored with zero month
+func (sqlite *SqliteDB) GetRecentBatchDates(limit int) ([]string, error) {
+	query := fmt.Sprintf(
+		"SELECT Date FROM %s WHERE substr(Date, 6, 2) != '00' ORDER BY Date DESC LIMIT ?;",
+		config.DatesTableName)
+
+	rows, err := sqlite.DB.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dates []string
+
+	for rows.Next() {
+		var date string
+		if err = rows.Scan(&date); err != nil {
+			return nil, err
+		}
+		dates = append(dates, date)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dates, nil
+}
+
+// GetUnpaidOrders returns unpaid and partially paid orders of given dates
+func (sqlite *SqliteDB) GetUnpaidOrders(dates []string, status string) ([]Order, error) {
+	if len(dates) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(dates))
+	args := []interface{}{fieldparser.PaymentStatusUnpaid, fieldparser.PaymentStatusPartial}
+	for i, date := range dates {
+		placeholders[i] = "?"
+		args = append(args, date)
+	}
+
+	condition, args := statusCondition(
+		fmt.Sprintf("PaymentStatus IN (?, ?) AND Date IN (%s)", strings.Join(placeholders, ", ")),
+		args, status)
+
+	query := fmt.Sprintf(
+		"SELECT %s FROM %s WHERE %s ORDER BY Date DESC, FirstRow ASC;",
+		orderColumns(), config.OrdersTableName, condition)
+
+	return executeOrdersQuery(sqlite, query, args...)
+}
+
+// GetDeliveryCounts returns number of orders per delivery method for every date within optional
+// inclusive range
+func (sqlite *SqliteDB) GetDeliveryCounts(from, to, status string) ([]DeliveryCount, error) {
+	condition, args := dateRangeCondition("Date", from, to)
+	condition, args = statusCondition(condition, args, status)
+
+	query := fmt.Sprintf(
+		`SELECT Date, DeliveryMethod, COUNT(*) FROM %s
+		WHERE %s
+		GROUP BY Date, DeliveryMethod
+		ORDER BY Date DESC, DeliveryMethod ASC;`,
+		config.OrdersTableName, condition)
+
+	rows, err := sqlite.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []DeliveryCount
+
+	for rows.Next() {
+		var count DeliveryCount
+		if err = rows.Scan(&count.Date, &count.DeliveryMethod, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// GetOrdersByDeliveryMethod returns orders of a given date with a given delivery method
+// ordered by delivery time window
+func (sqlite *SqliteDB) GetOrdersByDeliveryMethod(date, method, status string) ([]Order, error) {
+	condition, args := statusCondition(
+		"Date = ? AND DeliveryMethod = ?", []interface{}{date, method}, status)
+
+	query := fmt.Sprintf(
+		`SELECT %s FROM %s
+		WHERE %s
+		ORDER BY TimeFromParsed = '' ASC, TimeFromParsed ASC, TimeToParsed ASC, FirstRow ASC;`,
+		orderColumns(), config.OrdersTableName, condition)
+
+	return executeOrdersQuery(sqlite, query, args...)
+}
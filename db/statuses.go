@@ -0,0 +1,120 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+)
+
+func (sqlite *SqliteDB) initStatusHistoryTable() error {
+	createTableSQL := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			Date TEXT,
+			RowNumber INTEGER,
+			OrderID TEXT,
+			PreviousStatus TEXT,
+			Status TEXT,
+			ChangedAt TEXT
+		);
+	`, config.StatusHistoryTableName)
+
+	if _, err := sqlite.DB.Exec(createTableSQL); err != nil {
+		return fmt.Errorf("failed to create table %s: %w", config.StatusHistoryTableName, err)
+	}
+
+	createIndexSQL := fmt.Sprintf(
+		"CREATE INDEX IF NOT EXISTS idx_%s_date_row ON %s (Date, RowNumber);",
+		strings.ToLower(config.StatusHistoryTableName), config.StatusHistoryTableName)
+
+	if _, err := sqlite.DB.Exec(createIndexSQL); err != nil {
+		return fmt.Errorf("failed to create index on %s: %w", config.StatusHistoryTableName, err)
+	}
+
+	return nil
+}
+
+// GetRowsByDateWithTx returns stored rows of a sheet, it's called before the sheet is
+// rewritten to find status changes
+func (sqlite *SqliteDB) GetRowsByDateWithTx(tx *sql.Tx, date string) ([]Data, error) {
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE Date = ? ORDER BY RowNumber ASC;",
+		dataColumns(), config.DataTableName)
+
+	rows, err := tx.Query(query, date)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanData(rows)
+}
+
+func (sqlite *SqliteDB) InsertStatusChangesWithTx(tx *sql.Tx, changes []StatusChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+
+	insertSQL := fmt.Sprintf(
+		`INSERT INTO %s (Date, RowNumber, OrderID, PreviousStatus, Status, ChangedAt)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		config.StatusHistoryTableName)
+
+	statement, err := tx.Prepare(insertSQL)
+	if err != nil {
+		return fmt.Errorf("failed to prepare SQL statement: %w", err)
+	}
+	defer statement.Close()
+
+	for _, change := range changes {
+		_, err = statement.Exec(change.Date, change.RowNumber, change.OrderID,
+			change.PreviousStatus, change.Status, change.ChangedAt)
+		if err != nil {
+			return fmt.Errorf("failed to execute SQL statement: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetStatusHistory returns status changes of rows within optional inclusive batch date range,
+// optionally only changes to a given status
+func (sqlite *SqliteDB) GetStatusHistory(from, to, status string) ([]StatusChange, error) {
+	condition, args := dateRangeCondition("h.Date", from, to)
+	if status != "" {
+		condition += " AND h.Status = ?"
+		args = append(args, status)
+	}
+
+	query := fmt.Sprintf(
+		`SELECT h.Date, h.RowNumber, h.OrderID, h.PreviousStatus, h.Status, h.ChangedAt,
+			COALESCE(d.OrderLink, '')
+		FROM %s h
+		LEFT JOIN %s d ON d.Date = h.Date AND d.RowNumber = h.RowNumber
+		WHERE %s
+		ORDER BY h.ChangedAt DESC, h.Date DESC, h.RowNumber ASC;`,
+		config.StatusHistoryTableName, config.DataTableName, condition)
+
+	rows, err := sqlite.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []StatusChange{}
+
+	for rows.Next() {
+		var change StatusChange
+		err = rows.Scan(&change.Date, &change.RowNumber, &change.OrderID,
+			&change.PreviousStatus, &change.Status, &change.ChangedAt, &change.OrderLink)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,83 @@
+package essentialshandler
+
+import (
+	"fmt"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/db"
+)
+
+type EssentialsReport struct {
+	From        string
+	To          string
+	Kind        string
+	TotalOrders int
+	Items       []db.EssentialStats
+}
+
+// GetTopEssentials returns limit most frequent words, phrases or lemmas of batches within optional
+// inclusive date range with share of orders which inscriptions contain them
+func (handler *EssentialsHandler) GetTopEssentials(
+	from, to, kind string, limit int,
+) (*EssentialsReport, error) {
+
+	if kind == "" {
+		kind = config.EssentialKindWord
+	}
+	if !ValidKind(kind) {
+		return nil, fmt.Errorf("unknown essentials kind %q", kind)
+	}
+	if limit <= 0 {
+		limit = config.EssentialsDefaultLimit
+	}
+
+	totalOrders, err := handler.storage.GetEssentialsTotalOrders(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch total orders: %w", err)
+	}
+
+	items, err := handler.storage.GetEssentialStats(from, to, kind, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch essentials: %w", err)
+	}
+
+	for i := range items {
+		if totalOrders > 0 {
+			items[i].Share = float64(items[i].Orders) / float64(totalOrders)
+		}
+	}
+
+	if kind == config.EssentialKindLemma {
+		if err = handler.addLemmaForms(from, to, items); err != nil {
+			return nil, err
+		}
+	}
+
+	return &EssentialsReport{
+		From:        from,
+		To:          to,
+		Kind:        kind,
+		TotalOrders: totalOrders,
+		Items:       items,
+	}, nil
+}
+
+// addLemmaForms fills words of a date range normalized to every lemma, most frequent first
+func (handler *EssentialsHandler) addLemmaForms(from, to string, items []db.EssentialStats) error {
+	words, err := handler.storage.GetEssentialStats(from, to, config.EssentialKindWord, -1)
+	if err != nil {
+		return fmt.Errorf("failed to fetch essential words: %w", err)
+	}
+
+	forms := make(map[string][]string)
+	for _, word := range words {
+		lemma := handler.normalizeWord(word.Word)
+		forms[lemma] = append(forms[lemma], word.Word)
+	}
+
+	for i := range items {
+		items[i].Forms = forms[items[i].Word]
+	}
+
+	return nil
+}
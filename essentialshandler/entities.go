@@ -0,0 +1,158 @@
+package essentialshandler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/db"
+)
+
+// romanValues are values of roman numeral letters
+var romanValues = map[rune]int{'I': 1, 'V': 5, 'X': 10, 'L': 50, 'C': 100, 'D': 500, 'M': 1000}
+
+// ClassifyInscription returns entities of an inscription: engraved dates and years, coordinates,
+// roman numerals, personal names and symbols (♥, ∞, ...). Matched dates and coordinates are
+// removed from the text before looking for other entities, so their digits and "°" are not
+// counted twice
+func (handler *EssentialsHandler) ClassifyInscription(inscription string) []db.InscriptionEntity {
+	var entities []db.InscriptionEntity
+	add := func(entity, value string, year int) {
+		for _, existing := range entities {
+			if existing.Entity == entity && existing.Value == value {
+				return
+			}
+		}
+		entities = append(entities, db.InscriptionEntity{Entity: entity, Value: value, Year: year})
+	}
+
+	text := inscription
+
+	for _, match := range config.DMSCoordinatesRegex.FindAllStringSubmatch(text, -1) {
+		latitude := dmsDegrees(match[1], match[2], match[3], match[4])
+		longitude := dmsDegrees(match[5], match[6], match[7], match[8])
+		add(config.EntityCoordinates, fmt.Sprintf("%.6f, %.6f", latitude, longitude), 0)
+	}
+	text = config.DMSCoordinatesRegex.ReplaceAllString(text, " ")
+
+	for _, match := range config.DecimalCoordinatesRegex.FindAllStringSubmatch(text, -1) {
+		latitude, latErr := parseDegrees(match[1], match[2])
+		longitude, lonErr := parseDegrees(match[3], match[4])
+		if latErr != nil || lonErr != nil || latitude < -90 || latitude > 90 ||
+			longitude < -180 || longitude > 180 {
+			continue
+		}
+		add(config.EntityCoordinates, fmt.Sprintf("%.6f, %.6f", latitude, longitude), 0)
+		text = strings.Replace(text, match[0], " ", 1)
+	}
+
+	for _, match := range config.EngravedDateRegex.FindAllStringSubmatch(text, -1) {
+		date, ok := engravedDate(match[1], match[2], match[3])
+		if !ok {
+			continue
+		}
+		add(config.EntityDate, date.Format(config.DateLayout), date.Year())
+		text = strings.Replace(text, match[0], " ", 1)
+	}
+
+	for _, match := range config.YearRegex.FindAllString(text, -1) {
+		year, _ := strconv.Atoi(match)
+		add(config.EntityDate, match, year)
+	}
+
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '-'
+	}) {
+		word = strings.Trim(word, "-")
+
+		if utf8.RuneCountInString(word) > 1 && config.RomanNumeralRegex.MatchString(word) {
+			add(config.EntityRomanNumeral, strconv.Itoa(romanValue(word)), 0)
+			continue
+		}
+
+		if name, ok := handler.matchName(word); ok {
+			add(config.EntityName, name, 0)
+		}
+	}
+
+	for _, char := range text {
+		if unicode.In(char, unicode.So, unicode.Sm) {
+			add(config.EntitySymbol, string(char), 0)
+		}
+	}
+
+	return entities
+}
+
+// matchName returns a name of the name dictionary matching an uppercase word or its case form
+// ("МАШЕ" matches "МАША")
+func (handler *EssentialsHandler) matchName(word string) (string, bool) {
+	if containsLowercaseCyrillic(word) {
+		return "", false
+	}
+
+	name, ok := handler.names[strings.ReplaceAll(word, "Ё", "Е")]
+	return name, ok
+}
+
+// engravedDate parses day, month and 2 or 4 digit year, 2 digit years up to the current one
+// belong to this century
+func engravedDate(day, month, year string) (time.Time, bool) {
+	if len(year) == 2 {
+		century := "20"
+		if year > time.Now().Format("06") {
+			century = "19"
+		}
+		year = century + year
+	}
+
+	date, err := time.Parse("2.1.2006", fmt.Sprintf("%s.%s.%s", day, month, year))
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return date, true
+}
+
+// parseDegrees parses decimal degrees with optional hemisphere letter
+func parseDegrees(value, hemisphere string) (float64, error) {
+	degrees, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", "."), 64)
+	if err != nil {
+		return 0, err
+	}
+	return signedDegrees(degrees, hemisphere), nil
+}
+
+// dmsDegrees converts degrees, minutes and optional seconds to decimal degrees
+func dmsDegrees(degrees, minutes, seconds, hemisphere string) float64 {
+	d, _ := strconv.ParseFloat(degrees, 64)
+	m, _ := strconv.ParseFloat(minutes, 64)
+	s, _ := strconv.ParseFloat(strings.ReplaceAll(seconds, ",", "."), 64)
+	return signedDegrees(d+m/60+s/3600, hemisphere)
+}
+
+func signedDegrees(degrees float64, hemisphere string) float64 {
+	switch hemisphere {
+	case "S", "Ю", "W", "З":
+		return -degrees
+	}
+	return degrees
+}
+
+// romanValue converts a valid roman numeral to a number
+func romanValue(numeral string) int {
+	value := 0
+	runes := []rune(numeral)
+	for i, char := range runes {
+		if i+1 < len(runes) && romanValues[char] < romanValues[runes[i+1]] {
+			value -= romanValues[char]
+		} else {
+			value += romanValues[char]
+		}
+	}
+	return value
+}
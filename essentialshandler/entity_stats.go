@@ -0,0 +1,96 @@
+package essentialshandler
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+)
+
+// EntityStats is a number and share of rows of a product type with an entity in inscriptions
+// with the most frequent values (years for dates)
+type EntityStats struct {
+	Entity string
+	Rows   int
+	Share  float64
+	Values []ValueCount
+}
+
+type ValueCount struct {
+	Value string
+	Rows  int
+}
+
+// TypeEntities holds entity statistics of a product type, Rows is the number of its rows
+// with inscriptions
+type TypeEntities struct {
+	Type     string
+	Rows     int
+	Entities []EntityStats
+}
+
+type EntitiesReport struct {
+	From  string
+	To    string
+	Types []TypeEntities
+}
+
+// GetEntityStats returns entities found in inscriptions per product type of batches within
+// optional inclusive date range, eg share of "ПОДВЕСКА" rows with an engraved date
+func (handler *EssentialsHandler) GetEntityStats(from, to, productType string) (*EntitiesReport, error) {
+	typeRows, err := handler.storage.GetInscriptionTypeCounts(from, to, productType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rows with inscriptions: %w", err)
+	}
+
+	entityRows, err := handler.storage.GetEntityRowCounts(from, to, productType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch entity counts: %w", err)
+	}
+
+	valueCounts, err := handler.storage.GetEntityValueCounts(from, to, productType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch entity values: %w", err)
+	}
+
+	values := make(map[string]map[string][]ValueCount)
+	for _, count := range valueCounts {
+		if values[count.Type] == nil {
+			values[count.Type] = make(map[string][]ValueCount)
+		}
+		if len(values[count.Type][count.Entity]) < config.EntityTopValues {
+			values[count.Type][count.Entity] = append(values[count.Type][count.Entity],
+				ValueCount{Value: count.Value, Rows: count.Rows})
+		}
+	}
+
+	report := &EntitiesReport{From: from, To: to, Types: []TypeEntities{}}
+
+	for productType, rows := range typeRows {
+		typeEntities := TypeEntities{Type: productType, Rows: rows, Entities: []EntityStats{}}
+
+		for entity, entityCount := range entityRows[productType] {
+			typeEntities.Entities = append(typeEntities.Entities, EntityStats{
+				Entity: entity,
+				Rows:   entityCount,
+				Share:  share(entityCount, rows),
+				Values: values[productType][entity],
+			})
+		}
+
+		sort.Slice(typeEntities.Entities, func(i, j int) bool {
+			return typeEntities.Entities[i].Entity < typeEntities.Entities[j].Entity
+		})
+
+		report.Types = append(report.Types, typeEntities)
+	}
+
+	sort.Slice(report.Types, func(i, j int) bool {
+		if report.Types[i].Rows != report.Types[j].Rows {
+			return report.Types[i].Rows > report.Types[j].Rows
+		}
+		return report.Types[i].Type < report.Types[j].Type
+	})
+
+	return report, nil
+}
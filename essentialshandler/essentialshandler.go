@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"unicode"
 	"unicode/utf8"
 
 	"github.com/crush-on-anechka/ktn_stats/config"
@@ -12,39 +13,144 @@ import (
 
 type EssentialsHandler struct {
 	storage *db.SqliteDB
+	// lemmas maps word forms to lemmas of the lemma dictionary
+	lemmas map[string]string
+	// names maps case forms of personal names to names
+	names map[string]string
 }
 
 func New(storage *db.SqliteDB) *EssentialsHandler {
-	return &EssentialsHandler{storage: storage}
+	return &EssentialsHandler{
+		storage: storage,
+		lemmas:  loadLemmas(config.AppSettings.LemmaDictionaryFile),
+		names:   loadNames(config.AppSettings.NameDictionaryFile),
+	}
 }
 
 func (handler *EssentialsHandler) UpdateEssentialsByDate(date string) error {
-	inscriptions, err := handler.storage.GetInscriptionsByDate(date)
+	inscriptionRows, err := handler.storage.GetInscriptionsByDate(date)
 	if err != nil {
 		return fmt.Errorf("failed to fetch essentials from db: %w", err)
 	}
 
 	essentialWordsCount := make(map[string]int)
-
-	for _, inscription := range inscriptions {
-		words := handler.extractEssentialWords(inscription)
-		for _, word := range words {
-			essentialWordsCount[word]++
+	phrasesCount := make(map[string]int)
+	lemmasCount := make(map[string]int)
+	// orders by word, phrase and lemma, rows of old data without OrderID are counted as orders
+	wordOrders := make(map[string]map[string]bool)
+	phraseOrders := make(map[string]map[string]bool)
+	lemmaOrders := make(map[string]map[string]bool)
+	orders := make(map[string]bool)
+	var entities []db.InscriptionEntity
+
+	for _, row := range inscriptionRows {
+		orderID := row.OrderID
+		if orderID == "" {
+			orderID = fmt.Sprintf("%s-%d", date, row.RowNumber)
+		}
+		orders[orderID] = true
+
+		for _, inscription := range row.Inscriptions {
+			for _, word := range handler.extractEssentialWords(inscription) {
+				essentialWordsCount[word]++
+				addOrder(wordOrders, word, orderID)
+
+				lemma := handler.normalizeWord(word)
+				lemmasCount[lemma]++
+				addOrder(lemmaOrders, lemma, orderID)
+			}
+
+			for _, phrase := range handler.extractPhrases(inscription) {
+				phrasesCount[phrase]++
+				addOrder(phraseOrders, phrase, orderID)
+			}
+
+			for _, entity := range handler.ClassifyInscription(inscription) {
+				entity.Date, entity.RowNumber, entity.Type = date, row.RowNumber, row.Type
+				entities = append(entities, entity)
+			}
 		}
 	}
 
+	counts := essentialCounts(config.EssentialKindWord, essentialWordsCount, wordOrders)
+	counts = append(counts, essentialCounts(config.EssentialKindPhrase, phrasesCount, phraseOrders)...)
+	counts = append(counts, essentialCounts(config.EssentialKindLemma, lemmasCount, lemmaOrders)...)
+
 	essentialsJson, err := json.Marshal(essentialWordsCount)
 	if err != nil {
 		return fmt.Errorf("failed to marshal essentials map: %w", err)
 	}
 
-	if err = handler.storage.UpdateWords(date, string(essentialsJson)); err != nil {
+	phrasesJson, err := json.Marshal(phrasesCount)
+	if err != nil {
+		return fmt.Errorf("failed to marshal phrases map: %w", err)
+	}
+
+	lemmasJson, err := json.Marshal(lemmasCount)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lemmas map: %w", err)
+	}
+
+	tx, err := handler.storage.BeginTransaction()
+	if err != nil {
+		return err
+	}
+
+	if err = handler.storage.UpdateWordsWithTx(tx, date, string(essentialsJson)); err != nil {
+		tx.Rollback()
 		return fmt.Errorf("failed to update essentials for %s: %w", date, err)
 	}
 
+	if err = handler.storage.UpdatePhrasesWithTx(tx, date, string(phrasesJson)); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to update phrases for %s: %w", date, err)
+	}
+
+	if err = handler.storage.UpdateLemmasWithTx(tx, date, string(lemmasJson)); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to update lemmas for %s: %w", date, err)
+	}
+
+	if err = handler.storage.ReplaceEssentialsByDateWithTx(tx, date, counts, len(orders)); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to update essentials aggregates for %s: %w", date, err)
+	}
+
+	if err = handler.storage.ReplaceInscriptionEntitiesByDateWithTx(tx, date, entities); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to update inscription entities for %s: %w", date, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	return nil
 }
 
+func addOrder(ordersByWord map[string]map[string]bool, word, orderID string) {
+	if ordersByWord[word] == nil {
+		ordersByWord[word] = make(map[string]bool)
+	}
+	ordersByWord[word][orderID] = true
+}
+
+func essentialCounts(
+	kind string, wordsCount map[string]int, ordersByWord map[string]map[string]bool,
+) []db.EssentialCount {
+
+	counts := make([]db.EssentialCount, 0, len(wordsCount))
+	for word, count := range wordsCount {
+		counts = append(counts, db.EssentialCount{
+			Word:   word,
+			Kind:   kind,
+			Count:  count,
+			Orders: len(ordersByWord[word]),
+		})
+	}
+	return counts
+}
+
 func (handler *EssentialsHandler) extractEssentialWords(inscription string) []string {
 	var essentialWords []string
 
@@ -60,12 +166,59 @@ func (handler *EssentialsHandler) extractEssentialWords(inscription string) []st
 	return essentialWords
 }
 
+// extractPhrases returns 2 to config.PhraseMaxWords word phrases of an inscription. Line breaks
+// and lowercase words (comments rather than engraving) split an inscription into runs, phrases
+// never cross them. Unlike word counts, single letter words ("Я И ТЫ") are kept
+func (handler *EssentialsHandler) extractPhrases(inscription string) []string {
+	var phrases []string
+
+	lines := strings.FieldsFunc(inscription, func(r rune) bool { return r == '\n' || r == '\r' })
+
+	for _, line := range lines {
+		var run []string
+
+		for _, word := range append(strings.Fields(line), "") {
+			word = handler.cleanEssentialWord(word)
+			if containsLetterOrDigit(word) && !containsLowercaseCyrillic(word) {
+				run = append(run, word)
+				continue
+			}
+
+			phrases = append(phrases, runPhrases(run)...)
+			run = nil
+		}
+	}
+
+	return phrases
+}
+
+func runPhrases(run []string) []string {
+	var phrases []string
+
+	for size := config.PhraseMinWords; size <= config.PhraseMaxWords; size++ {
+		for start := 0; start+size <= len(run); start++ {
+			phrases = append(phrases, strings.Join(run[start:start+size], " "))
+		}
+	}
+
+	return phrases
+}
+
 func (handler *EssentialsHandler) cleanEssentialWord(s string) string {
 	s = strings.ReplaceAll(s, "\"", "")
 	s = strings.TrimRight(s, ".,:;!?")
 	return s
 }
 
+func containsLetterOrDigit(s string) bool {
+	for _, char := range s {
+		if unicode.IsLetter(char) || unicode.IsDigit(char) {
+			return true
+		}
+	}
+	return false
+}
+
 func containsLowercaseCyrillic(s string) bool {
 	return config.LowercaseCyrillicRegex.MatchString(s)
 }
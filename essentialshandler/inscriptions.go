@@ -0,0 +1,116 @@
+package essentialshandler
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/db"
+	"github.com/crush-on-anechka/ktn_stats/fieldparser"
+)
+
+type InscriptionMatch struct {
+	Date         string
+	RowNumber    int
+	Field        string
+	Inscription  string
+	CustomerLink string
+	FullName     string
+	Phone        string
+	Type         string
+	Subtype      string
+	OrderLink    string
+	SameCustomer bool
+}
+
+// CheckInscription looks for previous orders with an inscription identical to the given text
+// (after normalization) in any of the inscription fields. If customer (customer id, phone or
+// social link) is not empty, matches belonging to this customer are marked with SameCustomer
+func (handler *EssentialsHandler) CheckInscription(text, customer string) ([]InscriptionMatch, error) {
+	normalizedText := NormalizeInscription(text)
+	if normalizedText == "" {
+		return nil, nil
+	}
+
+	// "Е" may stand for "Ё" in stored inscriptions, so it is replaced with LIKE wildcard
+	searchWord := strings.ReplaceAll(longestWord(normalizedText), "Е", "_")
+
+	candidates, err := handler.storage.GetOrdersBySearchWord(searchWord)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch candidates from db: %w", err)
+	}
+
+	matches := []InscriptionMatch{}
+
+	for _, candidate := range candidates {
+		v := reflect.ValueOf(candidate)
+		t := v.Type()
+
+		for i := 0; i < t.NumField(); i++ {
+			fieldName := t.Field(i).Name
+			if !config.FieldsWithInscription[fieldName] {
+				continue
+			}
+
+			value := v.Field(i).String()
+			if NormalizeInscription(value) != normalizedText {
+				continue
+			}
+
+			matches = append(matches, InscriptionMatch{
+				Date:         candidate.Date,
+				RowNumber:    candidate.RowNumber,
+				Field:        fieldName,
+				Inscription:  value,
+				CustomerLink: candidate.CustomerLink,
+				FullName:     candidate.FullName,
+				Phone:        candidate.Phone,
+				Type:         candidate.Type,
+				Subtype:      candidate.Subtype,
+				OrderLink:    candidate.OrderLink,
+				SameCustomer: customer != "" && isSameCustomer(candidate, customer),
+			})
+		}
+	}
+
+	return matches, nil
+}
+
+// NormalizeInscription converts inscription to upper case, removes quotes and collapses
+// all whitespace including line breaks, so that equal engravings compare equal
+func NormalizeInscription(inscription string) string {
+	inscription = strings.ToUpper(inscription)
+	inscription = strings.NewReplacer("Ё", "Е", "\"", "", "«", "", "»", "", "“", "", "”", "").
+		Replace(inscription)
+	return strings.Join(strings.Fields(inscription), " ")
+}
+
+func longestWord(s string) string {
+	var longest string
+	for _, word := range strings.Fields(s) {
+		if utf8.RuneCountInString(word) > utf8.RuneCountInString(longest) {
+			longest = word
+		}
+	}
+	return longest
+}
+
+// isSameCustomer compares normalized keys of the order with customer given as customer id,
+// phone number in any format or social link (a bare handle is taken from the order's network)
+func isSameCustomer(order db.Data, customer string) bool {
+	customer = strings.TrimSpace(customer)
+
+	if order.CustomerID != 0 && customer == strconv.Itoa(order.CustomerID) {
+		return true
+	}
+
+	if phone, ok := fieldparser.NormalizePhone(customer); ok && phone != "" && phone == order.PhoneNormalized {
+		return true
+	}
+
+	network, handle := fieldparser.ParseSocialLink(customer, order.Socials)
+	return handle != "" && network == order.SocialNetwork && handle == order.SocialHandle
+}
@@ -0,0 +1,63 @@
+package essentialshandler
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+)
+
+// loadLemmas reads lemma dictionary where every line holds a lemma followed by its forms
+// ("ЛЮБИТЬ ЛЮБЛЮ ЛЮБИШЬ ЛЮБИМАЯ"), lines starting with "#" are skipped. Returns forms mapped
+// to lemmas, empty if path is empty or the file can't be read
+func loadLemmas(path string) map[string]string {
+	lemmas := make(map[string]string)
+	if path == "" {
+		return lemmas
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Printf("Error reading lemma dictionary, using stemmer only: %v", err)
+		return lemmas
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		words := strings.Fields(strings.ToUpper(strings.ReplaceAll(line, ",", " ")))
+		for _, form := range words {
+			lemmas[form] = words[0]
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		log.Printf("Error reading lemma dictionary: %v", err)
+	}
+
+	return lemmas
+}
+
+// normalizeWord returns lemma of an essential word from the lemma dictionary or its stem
+func (handler *EssentialsHandler) normalizeWord(word string) string {
+	if lemma, ok := handler.lemmas[word]; ok {
+		return lemma
+	}
+	return stemRussian(word)
+}
+
+// ValidKind reports whether kind is one of essentials kinds
+func ValidKind(kind string) bool {
+	switch kind {
+	case config.EssentialKindWord, config.EssentialKindPhrase, config.EssentialKindLemma:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,234 @@
+package essentialshandler
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/db"
+	"github.com/crush-on-anechka/ktn_stats/fieldparser"
+)
+
+// percentiles of inscription length reported in LengthStats.Percentiles
+var lengthPercentiles = []int{50, 90, 95, 99}
+
+// LengthStats describes inscriptions of a field of a product type (and subtype if grouped by
+// subtype). Length is a number of characters without line breaks, Percentiles are keyed by
+// "p50", "p90", ... CharClasses are numbers of inscriptions containing a character class,
+// SpecialChars - numbers of inscriptions containing the most frequent characters other than
+// letters, digits and spaces
+type LengthStats struct {
+	Type          string
+	Subtype       string
+	Field         string
+	Inscriptions  int
+	MinLength     int
+	MaxLength     int
+	Percentiles   map[string]int
+	MaxLines      int
+	MaxLineLength int
+	Histogram     []HistogramBucket
+	CharClasses   map[string]int
+	SpecialChars  []ValueCount
+}
+
+// HistogramBucket is a number of inscriptions with length from From to To inclusive
+type HistogramBucket struct {
+	From  int
+	To    int
+	Count int
+}
+
+type LengthsReport struct {
+	From  string
+	To    string
+	Items []LengthStats
+}
+
+// GetLengthStats returns length and character set statistics of inscription fields per product
+// type (and subtype if bySubtype) of batches within optional inclusive date range
+func (handler *EssentialsHandler) GetLengthStats(
+	from, to, productType string, bySubtype bool,
+) (*LengthsReport, error) {
+
+	rows, err := handler.storage.GetInscriptionFields(from, to, productType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch inscriptions: %w", err)
+	}
+
+	type groupKey struct{ productType, subtype, field string }
+	groups := make(map[groupKey][]string)
+
+	for _, row := range rows {
+		for field, value := range row.Fields {
+			key := groupKey{productType: row.Type, field: field}
+			if bySubtype {
+				key.subtype = row.Subtype
+			}
+			groups[key] = append(groups[key], value)
+		}
+	}
+
+	report := &LengthsReport{From: from, To: to, Items: []LengthStats{}}
+	for key, inscriptions := range groups {
+		stats := lengthStats(inscriptions)
+		stats.Type, stats.Subtype, stats.Field = key.productType, key.subtype, key.field
+		report.Items = append(report.Items, stats)
+	}
+
+	fieldOrder := make(map[string]int)
+	for i, field := range db.InscriptionFieldnames() {
+		fieldOrder[field] = i
+	}
+
+	sort.Slice(report.Items, func(i, j int) bool {
+		a, b := report.Items[i], report.Items[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Subtype != b.Subtype {
+			return a.Subtype < b.Subtype
+		}
+		return fieldOrder[a.Field] < fieldOrder[b.Field]
+	})
+
+	return report, nil
+}
+
+func lengthStats(inscriptions []string) LengthStats {
+	stats := LengthStats{
+		Inscriptions: len(inscriptions),
+		Percentiles:  make(map[string]int),
+		CharClasses:  make(map[string]int),
+	}
+
+	lengths := make([]int, 0, len(inscriptions))
+	specialChars := make(map[string]int)
+
+	for _, inscription := range inscriptions {
+		lines := strings.FieldsFunc(inscription, func(r rune) bool { return r == '\n' || r == '\r' })
+
+		length := 0
+		for _, line := range lines {
+			lineLength := utf8.RuneCountInString(line)
+			length += lineLength
+			if lineLength > stats.MaxLineLength {
+				stats.MaxLineLength = lineLength
+			}
+		}
+		lengths = append(lengths, length)
+
+		if len(lines) > stats.MaxLines {
+			stats.MaxLines = len(lines)
+		}
+
+		classes := make(map[string]bool)
+		chars := make(map[string]bool)
+		for _, line := range lines {
+			for _, char := range line {
+				class := fieldparser.CharClass(char)
+				classes[class] = true
+				if fieldparser.IsSpecialCharClass(class) {
+					chars[string(char)] = true
+				}
+			}
+		}
+		for class := range classes {
+			stats.CharClasses[class]++
+		}
+		for char := range chars {
+			specialChars[char]++
+		}
+	}
+
+	sort.Ints(lengths)
+	stats.MinLength, stats.MaxLength = lengths[0], lengths[len(lengths)-1]
+	for _, percentile := range lengthPercentiles {
+		// nearest-rank percentile
+		rank := (percentile*len(lengths) + 99) / 100
+		stats.Percentiles[fmt.Sprintf("p%d", percentile)] = lengths[rank-1]
+	}
+
+	for _, length := range lengths {
+		bucketFrom := length / config.LengthHistogramBucket * config.LengthHistogramBucket
+		if len(stats.Histogram) == 0 || stats.Histogram[len(stats.Histogram)-1].From != bucketFrom {
+			stats.Histogram = append(stats.Histogram, HistogramBucket{
+				From: bucketFrom,
+				To:   bucketFrom + config.LengthHistogramBucket - 1,
+			})
+		}
+		stats.Histogram[len(stats.Histogram)-1].Count++
+	}
+
+	for char, count := range specialChars {
+		stats.SpecialChars = append(stats.SpecialChars, ValueCount{Value: char, Rows: count})
+	}
+	sort.Slice(stats.SpecialChars, func(i, j int) bool {
+		if stats.SpecialChars[i].Rows != stats.SpecialChars[j].Rows {
+			return stats.SpecialChars[i].Rows > stats.SpecialChars[j].Rows
+		}
+		return stats.SpecialChars[i].Value < stats.SpecialChars[j].Value
+	})
+	if len(stats.SpecialChars) > config.SpecialCharsLimit {
+		stats.SpecialChars = stats.SpecialChars[:config.SpecialCharsLimit]
+	}
+
+	return stats
+}
+
+// WriteCSV writes the report as CSV with a row per product type and field, histogram and
+// special characters are written as "from-to:count" and "char:count" lists
+func (report *LengthsReport) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"Type", "Subtype", "Field", "Inscriptions", "MinLength", "MaxLength"}
+	for _, percentile := range lengthPercentiles {
+		header = append(header, fmt.Sprintf("p%d", percentile))
+	}
+	header = append(header, "MaxLines", "MaxLineLength", "Histogram")
+	header = append(header, fieldparser.CharClasses...)
+	header = append(header, "SpecialChars")
+
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, stats := range report.Items {
+		record := []string{
+			stats.Type, stats.Subtype, stats.Field, strconv.Itoa(stats.Inscriptions),
+			strconv.Itoa(stats.MinLength), strconv.Itoa(stats.MaxLength),
+		}
+		for _, percentile := range lengthPercentiles {
+			record = append(record, strconv.Itoa(stats.Percentiles[fmt.Sprintf("p%d", percentile)]))
+		}
+
+		histogram := make([]string, len(stats.Histogram))
+		for i, bucket := range stats.Histogram {
+			histogram[i] = fmt.Sprintf("%d-%d:%d", bucket.From, bucket.To, bucket.Count)
+		}
+		record = append(record, strconv.Itoa(stats.MaxLines), strconv.Itoa(stats.MaxLineLength),
+			strings.Join(histogram, " "))
+
+		for _, class := range fieldparser.CharClasses {
+			record = append(record, strconv.Itoa(stats.CharClasses[class]))
+		}
+
+		specialChars := make([]string, len(stats.SpecialChars))
+		for i, char := range stats.SpecialChars {
+			specialChars[i] = fmt.Sprintf("%s:%d", char.Value, char.Rows)
+		}
+		record = append(record, strings.Join(specialChars, " "))
+
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,92 @@
+package essentialshandler
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"strings"
+)
+
+// defaultNames are common personal names and their short forms recognized in inscriptions,
+// names from the name dictionary file (settings "nameDictionaryFile") are added to them. Names
+// which are common words of inscriptions or have such forms ("ЛЮБОВЬ", "ВЕРА", "НАДЕЖДА", "ЛЕВ",
+// "ЛЮБА" - "ЛЮБИ", "МИЛА" - "МИЛОЙ") are left out
+var defaultNames = []string{
+	"АЛЕКСАНДР", "АЛЕКСАНДРА", "САША", "АЛЕКСЕЙ", "ЛЁША", "АНАСТАСИЯ", "НАСТЯ", "АНДРЕЙ",
+	"АННА", "АНЯ", "АРТЁМ", "АРТЕМ", "ВАЛЕРИЯ", "ЛЕРА", "ВАРВАРА", "ВАРЯ", "ВАСИЛИЙ", "ВАСЯ",
+	"ВИКТОР", "ВИКТОРИЯ", "ВИКА", "ВЛАДИМИР", "ВОВА", "ВЛАДИСЛАВ", "ВЛАД", "ДАНИИЛ",
+	"ДАНЯ", "ДАРЬЯ", "ДАША", "ДЕНИС", "ДМИТРИЙ", "ДИМА", "ЕВГЕНИЙ", "ЕВГЕНИЯ", "ЖЕНЯ",
+	"ЕКАТЕРИНА", "КАТЯ", "ЕЛЕНА", "ЛЕНА", "ЕЛИЗАВЕТА", "ЛИЗА", "ИВАН", "ВАНЯ", "ИГОРЬ", "ИЛЬЯ",
+	"ИРИНА", "ИРА", "КИРИЛЛ", "КСЕНИЯ", "КСЮША", "ЛЮДМИЛА", "ЛЮДА", "МАКСИМ",
+	"МАРИНА", "МАРИЯ", "МАША", "МИХАИЛ", "МИША", "НАДЯ", "НАТАЛЬЯ", "НАТАША", "НИКИТА",
+	"НИКОЛАЙ", "КОЛЯ", "ОКСАНА", "ОЛЬГА", "ОЛЯ", "ПАВЕЛ", "ПАША", "ПОЛИНА", "РОМАН", "РОМА",
+	"СВЕТЛАНА", "СВЕТА", "СЕРГЕЙ", "СЕРЁЖА", "СОФИЯ", "СОФЬЯ", "СОНЯ", "ТАТЬЯНА", "ТАНЯ",
+	"ТИМОФЕЙ", "ТИМУР", "ЮЛИЯ", "ЮЛЯ", "ЮРИЙ", "ЯНА", "ЯРОСЛАВ", "АЛИСА", "ЕВА",
+	"МАРК", "ФЁДОР", "ФЕДЯ", "МАТВЕЙ", "ГЛЕБ", "ЕГОР", "АРИНА", "ВЕРОНИКА",
+}
+
+// nameEndings are case endings of names by their last letter, "" stands for names ending
+// with other consonants
+var nameEndings = map[string][]string{
+	"А": {"Ы", "И", "Е", "У", "ОЙ", "ЕЙ"},
+	"Я": {"И", "Е", "Ю", "ЕЙ", "ЕЮ"},
+	"Й": {"Я", "Ю", "Е", "И", "ЕМ"},
+	"Ь": {"Я", "Ю", "Е", "ЕМ"},
+	"":  {"А", "У", "Е", "ОМ", "ЕМ"},
+}
+
+// loadNames returns forms of default names and names from the name dictionary file (names are
+// separated by line breaks, spaces or commas) mapped to the names
+func loadNames(path string) map[string]string {
+	names := make(map[string]string)
+	for _, name := range defaultNames {
+		addNameForms(names, name)
+	}
+
+	if path == "" {
+		return names
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		log.Printf("Error reading name dictionary, using default names: %v", err)
+		return names
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		for _, name := range strings.Fields(strings.ReplaceAll(scanner.Text(), ",", " ")) {
+			addNameForms(names, name)
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		log.Printf("Error reading name dictionary: %v", err)
+	}
+
+	return names
+}
+
+// addNameForms adds a name with its case forms ("МАША", "МАШИ", "МАШЕ", ...), forms of
+// previously added names are kept
+func addNameForms(names map[string]string, name string) {
+	name = strings.ReplaceAll(strings.ToUpper(name), "Ё", "Е")
+	runes := []rune(name)
+	if len(runes) < 2 {
+		return
+	}
+
+	base, last := string(runes[:len(runes)-1]), string(runes[len(runes)-1])
+	endings, ok := nameEndings[last]
+	if !ok {
+		base, endings = name, nameEndings[""]
+	}
+
+	names[name] = name
+	for _, ending := range endings {
+		if _, exists := names[base+ending]; !exists {
+			names[base+ending] = name
+		}
+	}
+}
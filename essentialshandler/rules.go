@@ -0,0 +1,65 @@
+package essentialshandler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/db"
+)
+
+// RuleReport lists rows which inscriptions are excluded from essentials by an exclude rule
+type RuleReport struct {
+	Rule       config.EssentialsRule
+	Rows       int
+	Exclusions []db.RuleExclusion
+}
+
+// GetRuleReports returns rows excluded by every exclude rule of essentials rules from settings
+func (handler *EssentialsHandler) GetRuleReports() ([]RuleReport, error) {
+	rules := config.AppSettings.EssentialsRules
+
+	var reports []RuleReport
+
+	for i, rule := range rules {
+		if rule.Action != config.EssentialsRuleExclude {
+			continue
+		}
+
+		exclusions, err := handler.storage.GetEssentialsRuleExclusions(rules, i)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch rows excluded by rule %q: %w", rule.Name, err)
+		}
+
+		report := RuleReport{Rule: rule, Exclusions: exclusions}
+		for _, exclusion := range exclusions {
+			report.Rows += exclusion.Rows
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// FormatRuleReports renders rule reports as a Telegram message with the most frequent
+// "Тип" and "Вид" of excluded rows
+func FormatRuleReports(reports []RuleReport) string {
+	var builder strings.Builder
+	builder.WriteString("Essentials rules:")
+
+	for _, report := range reports {
+		builder.WriteString(fmt.Sprintf("\n\n%q excludes %d rows", report.Rule.Name, report.Rows))
+
+		for i, exclusion := range report.Exclusions {
+			if i == config.EssentialsRuleSamples {
+				builder.WriteString(fmt.Sprintf("\n- and %d more",
+					len(report.Exclusions)-config.EssentialsRuleSamples))
+				break
+			}
+			builder.WriteString(fmt.Sprintf("\n- %s / %s: %d", exclusion.Type, exclusion.Subtype, exclusion.Rows))
+		}
+	}
+
+	return builder.String()
+}
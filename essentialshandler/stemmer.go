@@ -0,0 +1,148 @@
+package essentialshandler
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Russian Snowball stemmer (https://snowballstem.org/algorithms/russian/stemmer.html).
+// Endings of every group are matched longest first, endings of "…Preceded" groups must follow
+// "а" or "я" which is kept
+
+var (
+	perfectiveGerundPreceded = []string{"в", "вши", "вшись"}
+	perfectiveGerund         = []string{"ив", "ивши", "ившись", "ыв", "ывши", "ывшись"}
+	adjective                = []string{
+		"ее", "ие", "ые", "ое", "ими", "ыми", "ей", "ий", "ый", "ой", "ем", "им", "ым", "ом",
+		"его", "ого", "ему", "ому", "их", "ых", "ую", "юю", "ая", "яя", "ою", "ею",
+	}
+	participlePreceded = []string{"ем", "нн", "вш", "ющ", "щ"}
+	participle         = []string{"ивш", "ывш", "ующ"}
+	reflexive          = []string{"ся", "сь"}
+	verbPreceded       = []string{
+		"ла", "на", "ете", "йте", "ли", "й", "л", "ем", "н", "ло", "но", "ет", "ют", "ны", "ть",
+		"ешь", "нно",
+	}
+	verb = []string{
+		"ила", "ыла", "ена", "ейте", "уйте", "ите", "или", "ыли", "ей", "уй", "ил", "ыл", "им",
+		"ым", "ен", "ило", "ыло", "ено", "ят", "ует", "уют", "ит", "ыт", "ены", "ить", "ыть",
+		"ишь", "ую", "ю",
+	}
+	noun = []string{
+		"а", "ев", "ов", "ие", "ье", "е", "иями", "ями", "ами", "еи", "ии", "и", "ией", "ей",
+		"ой", "ий", "й", "иям", "ям", "ием", "ем", "ам", "ом", "о", "у", "ах", "иях", "ях", "ы",
+		"ь", "ию", "ью", "ю", "ия", "ья", "я",
+	}
+	superlative   = []string{"ейш", "ейше"}
+	derivational  = []string{"ост", "ость"}
+	russianVowels = "аеиоуыэюя"
+)
+
+// stemRussian returns stem of an uppercase cyrillic word, other words are returned as is
+func stemRussian(word string) string {
+	for _, char := range word {
+		if !unicode.Is(unicode.Cyrillic, char) && char != '-' {
+			return word
+		}
+	}
+
+	runes := []rune(strings.ReplaceAll(strings.ToLower(word), "ё", "е"))
+	rv, r2 := russianRegions(runes)
+
+	if end, ok := stemEnding(runes, rv, perfectiveGerundPreceded, perfectiveGerund); ok {
+		runes = runes[:end]
+	} else {
+		if end, ok := stemEnding(runes, rv, nil, reflexive); ok {
+			runes = runes[:end]
+		}
+
+		if end, ok := stemEnding(runes, rv, nil, adjective); ok {
+			runes = runes[:end]
+			if end, ok := stemEnding(runes, rv, participlePreceded, participle); ok {
+				runes = runes[:end]
+			}
+		} else if end, ok := stemEnding(runes, rv, verbPreceded, verb); ok {
+			runes = runes[:end]
+		} else if end, ok := stemEnding(runes, rv, nil, noun); ok {
+			runes = runes[:end]
+		}
+	}
+
+	if end, ok := stemEnding(runes, rv, nil, []string{"и"}); ok {
+		runes = runes[:end]
+	}
+
+	if end, ok := stemEnding(runes, r2, nil, derivational); ok {
+		runes = runes[:end]
+	}
+
+	if end, ok := stemEnding(runes, rv, nil, superlative); ok {
+		runes = runes[:end]
+	}
+	if end, ok := stemEnding(runes, rv, nil, []string{"нн"}); ok {
+		runes = runes[:end+1]
+	} else if end, ok := stemEnding(runes, rv, nil, []string{"ь"}); ok {
+		runes = runes[:end]
+	}
+
+	return strings.ToUpper(string(runes))
+}
+
+// russianRegions returns start of RV (after the first vowel) and R2 regions
+func russianRegions(runes []rune) (int, int) {
+	isVowel := func(char rune) bool { return strings.ContainsRune(russianVowels, char) }
+
+	rv := len(runes)
+	for i, char := range runes {
+		if isVowel(char) {
+			rv = i + 1
+			break
+		}
+	}
+
+	region := func(start int) int {
+		for i := start + 1; i < len(runes); i++ {
+			if !isVowel(runes[i]) && isVowel(runes[i-1]) {
+				return i + 1
+			}
+		}
+		return len(runes)
+	}
+
+	return rv, region(region(0))
+}
+
+// stemEnding finds the longest ending of both groups within a region and returns position
+// it starts at. The ending isn't removed if it is of preceded group and doesn't follow
+// "а" or "я" within the region
+func stemEnding(runes []rune, region int, preceded, endings []string) (int, bool) {
+	start, isPreceded := len(runes)+1, false
+
+	match := func(ending string) int {
+		if !strings.HasSuffix(string(runes), ending) {
+			return len(runes) + 1
+		}
+		return len(runes) - len([]rune(ending))
+	}
+
+	for _, ending := range preceded {
+		if endingStart := match(ending); endingStart >= region && endingStart < start {
+			start, isPreceded = endingStart, true
+		}
+	}
+	for _, ending := range endings {
+		if endingStart := match(ending); endingStart >= region && endingStart < start {
+			start, isPreceded = endingStart, false
+		}
+	}
+
+	if start > len(runes) {
+		return 0, false
+	}
+
+	if isPreceded && (start-1 < region || (runes[start-1] != 'а' && runes[start-1] != 'я')) {
+		return 0, false
+	}
+
+	return start, true
+}
@@ -0,0 +1,189 @@
+package essentialshandler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+)
+
+// Trend compares share of orders containing a word or phrase with the same period a year
+// before. Growth is a ratio of shares with add-one smoothing, so words which were not used
+// before still get a finite growth depending on how often they are used now. New is true
+// if the word first appeared within the period
+type Trend struct {
+	Word           string
+	Orders         int
+	PreviousOrders int
+	Share          float64
+	PreviousShare  float64
+	Growth         float64
+	New            bool
+	FirstSeen      string
+}
+
+type TrendsReport struct {
+	From                string
+	To                  string
+	PreviousFrom        string
+	PreviousTo          string
+	Kind                string
+	TotalOrders         int
+	PreviousTotalOrders int
+	Items               []Trend
+}
+
+// GetTrends ranks words or phrases (kind) used in at least minSupport orders of batches
+// between from and to by growth compared with the same period a year before. Period defaults
+// to config.TrendsDefaultDays days before today
+func (handler *EssentialsHandler) GetTrends(
+	from, to, kind string, minSupport, limit int,
+) (*TrendsReport, error) {
+
+	if kind == "" {
+		kind = config.EssentialKindWord
+	}
+	if !ValidKind(kind) {
+		return nil, fmt.Errorf("unknown essentials kind %q", kind)
+	}
+	if minSupport <= 0 {
+		minSupport = config.TrendsMinSupport
+	}
+	if limit <= 0 {
+		limit = config.EssentialsDefaultLimit
+	}
+
+	fromDate, toDate, err := trendPeriod(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &TrendsReport{
+		From:         fromDate.Format(config.DateLayout),
+		To:           toDate.Format(config.DateLayout),
+		PreviousFrom: fromDate.AddDate(-1, 0, 0).Format(config.DateLayout),
+		PreviousTo:   toDate.AddDate(-1, 0, 0).Format(config.DateLayout),
+		Kind:         kind,
+		Items:        []Trend{},
+	}
+
+	report.TotalOrders, err = handler.storage.GetEssentialsTotalOrders(report.From, report.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch total orders: %w", err)
+	}
+
+	report.PreviousTotalOrders, err = handler.storage.GetEssentialsTotalOrders(
+		report.PreviousFrom, report.PreviousTo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch previous total orders: %w", err)
+	}
+
+	current, err := handler.storage.GetEssentialStats(report.From, report.To, kind, -1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch essentials: %w", err)
+	}
+
+	previous, err := handler.storage.GetEssentialStats(report.PreviousFrom, report.PreviousTo, kind, -1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch previous essentials: %w", err)
+	}
+
+	previousOrders := make(map[string]int, len(previous))
+	for _, stats := range previous {
+		previousOrders[stats.Word] = stats.Orders
+	}
+
+	for _, stats := range current {
+		if stats.Orders < minSupport {
+			continue
+		}
+
+		trend := Trend{
+			Word:           stats.Word,
+			Orders:         stats.Orders,
+			PreviousOrders: previousOrders[stats.Word],
+			Share:          share(stats.Orders, report.TotalOrders),
+			PreviousShare:  share(previousOrders[stats.Word], report.PreviousTotalOrders),
+			New:            stats.FirstSeen >= report.From,
+			FirstSeen:      stats.FirstSeen,
+		}
+		trend.Growth = share(trend.Orders+1, report.TotalOrders+2) /
+			share(trend.PreviousOrders+1, report.PreviousTotalOrders+2)
+
+		report.Items = append(report.Items, trend)
+	}
+
+	sort.Slice(report.Items, func(i, j int) bool {
+		if report.Items[i].Growth != report.Items[j].Growth {
+			return report.Items[i].Growth > report.Items[j].Growth
+		}
+		return report.Items[i].Word < report.Items[j].Word
+	})
+
+	if len(report.Items) > limit {
+		report.Items = report.Items[:limit]
+	}
+
+	return report, nil
+}
+
+// FormatTrendsDigest renders trends reports as a Telegram message
+func FormatTrendsDigest(reports ...*TrendsReport) string {
+	if len(reports) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Weekly digest: trending inscriptions %s - %s vs %s - %s",
+		reports[0].From, reports[0].To, reports[0].PreviousFrom, reports[0].PreviousTo))
+
+	for _, report := range reports {
+		builder.WriteString(fmt.Sprintf("\n\n%ss (%d orders, %d a year before):",
+			report.Kind, report.TotalOrders, report.PreviousTotalOrders))
+
+		if len(report.Items) == 0 {
+			builder.WriteString("\n- nothing above minimum support")
+		}
+
+		for _, trend := range report.Items {
+			builder.WriteString(fmt.Sprintf("\n- %s: %d orders (%d before), x%.1f",
+				trend.Word, trend.Orders, trend.PreviousOrders, trend.Growth))
+			if trend.New {
+				builder.WriteString(", new")
+			}
+		}
+	}
+
+	return builder.String()
+}
+
+func trendPeriod(from, to string) (time.Time, time.Time, error) {
+	toDate := time.Now()
+	if to != "" {
+		parsed, err := time.Parse(config.DateLayout, to)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w %q: %v", config.ErrInvalidDate, to, err)
+		}
+		toDate = parsed
+	}
+
+	fromDate := toDate.AddDate(0, 0, -config.TrendsDefaultDays)
+	if from != "" {
+		parsed, err := time.Parse(config.DateLayout, from)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w %q: %v", config.ErrInvalidDate, from, err)
+		}
+		fromDate = parsed
+	}
+
+	return fromDate, toDate, nil
+}
+
+func share(orders, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(orders) / float64(total)
+}
@@ -0,0 +1,150 @@
+package fieldparser
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	cityConfidence     = 30
+	streetConfidence   = 30
+	houseConfidence    = 30
+	postCodeConfidence = 10
+	unknownPartPenalty = 10
+)
+
+type Address struct {
+	Region     string
+	City       string
+	Street     string
+	House      string
+	Apartment  string
+	PostCode   string
+	Confidence int
+}
+
+var (
+	postCodeRegex  = regexp.MustCompile(`(?:^|\D)(\d{6})(?:\D|$)`)
+	regionRegex    = regexp.MustCompile(`(?i)(^|\s)(обл\.?|область|край|респ\.?|республика|ао|автономный округ)($|\s)`)
+	cityRegex      = regexp.MustCompile(`(?i)^(?:(?:г|пгт|пос|дер)(?:\.|\s)|(?:гор|п|с)\.|(?:город|посёлок|поселок|село|деревня|ст-ца|станица)\s)\s*`)
+	streetRegex    = regexp.MustCompile(`(?i)(^|\s)(ул\.?|улица|пр-т|пр-кт|просп\.?|проспект|пр\.|пер\.?|переулок|ш\.|шоссе|б-р|бульвар|наб\.?|набережная|пл\.|площадь|проезд|мкр\.?|микрорайон|туп\.?|тупик|аллея|линия)($|\s)`)
+	houseRegex     = regexp.MustCompile(`(?i)^(?:д\.?|дом)?\s*(\d+[а-я]?(?:\s*[/\-]\s*\d+[а-я]?)?(?:\s*(?:к|корп\.?|корпус|стр\.?|строение|с)\s*\d+)?)$`)
+	apartmentRegex = regexp.MustCompile(`(?i)^(?:кв\.?|квартира|оф\.?|офис|пом\.?|помещение)\s*(\d+[а-я]?)$`)
+	streetTail     = regexp.MustCompile(`(?i)^(.+?)\s+(?:д\.?|дом)?\s*(\d+[а-я]?(?:/\d+)?(?:\s*(?:к|корп\.?|стр\.?)\s*\d+)?)(?:\s*(?:-|кв\.?)\s*(\d+))?$`)
+	federalCities  = map[string]bool{
+		"москва":          true,
+		"санкт-петербург": true,
+		"спб":             true,
+		"севастополь":     true,
+	}
+)
+
+// ParseAddress splits a free text Russian delivery address into region, city, street, house,
+// apartment and post code. city ("Город" column) is used when the address doesn't contain
+// a city. Confidence (0-100) shows how much of the address was recognized
+func ParseAddress(address, city string) Address {
+	var result Address
+	unknownParts := 0
+
+	parts := strings.FieldsFunc(address, func(r rune) bool { return r == ',' || r == ';' || r == '\n' })
+
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		if match := postCodeRegex.FindStringSubmatch(part); match != nil && result.PostCode == "" {
+			result.PostCode = match[1]
+			part = strings.TrimSpace(strings.Replace(part, match[1], "", 1))
+			if part == "" {
+				continue
+			}
+		}
+
+		cityName := strings.TrimSpace(cityRegex.ReplaceAllString(part, ""))
+
+		switch {
+		case federalCities[strings.ToLower(cityName)]:
+			result.City = cityName
+			result.Region = cityName
+
+		case regionRegex.MatchString(part):
+			result.Region = part
+
+		case cityRegex.MatchString(part) && !houseRegex.MatchString(part):
+			result.City = cityName
+
+		case streetRegex.MatchString(part):
+			// addresses without commas may have a city before the street marker
+			if idx := streetRegex.FindStringIndex(part); idx[0] > 0 && result.City == "" {
+				result.City = strings.TrimSpace(cityRegex.ReplaceAllString(part[:idx[0]], ""))
+				part = strings.TrimSpace(part[idx[0]:])
+			}
+
+			result.Street = part
+			// "ул. Ленина 5-12" contains house and apartment after the street name
+			if match := streetTail.FindStringSubmatch(part); match != nil {
+				result.Street = strings.TrimSpace(match[1])
+				result.House = match[2]
+				if match[3] != "" {
+					result.Apartment = match[3]
+				}
+			}
+
+		case apartmentRegex.MatchString(part):
+			result.Apartment = apartmentRegex.FindStringSubmatch(part)[1]
+
+		case houseRegex.MatchString(part) && result.House == "":
+			result.House = strings.TrimSpace(houseRegex.FindStringSubmatch(part)[1])
+
+		case result.City == "" && result.Street == "" && !strings.ContainsAny(part, "0123456789"):
+			// a bare name before the street is most likely a city
+			result.City = part
+
+		default:
+			unknownParts++
+		}
+	}
+
+	if result.City == "" && strings.TrimSpace(city) != "" {
+		result.City = strings.TrimSpace(cityRegex.ReplaceAllString(strings.TrimSpace(city), ""))
+	}
+
+	result.Confidence = addressConfidence(result, unknownParts)
+
+	return result
+}
+
+// NormalizePostCode returns six digit post code from a "Индекс" value or empty string
+func NormalizePostCode(postCode string) string {
+	match := postCodeRegex.FindStringSubmatch(strings.ReplaceAll(postCode, " ", ""))
+	if match == nil {
+		return ""
+	}
+	return match[1]
+}
+
+func addressConfidence(address Address, unknownParts int) int {
+	confidence := 0
+
+	if address.City != "" {
+		confidence += cityConfidence
+	}
+	if address.Street != "" {
+		confidence += streetConfidence
+	}
+	if address.House != "" {
+		confidence += houseConfidence
+	}
+	if address.PostCode != "" {
+		confidence += postCodeConfidence
+	}
+
+	confidence -= unknownParts * unknownPartPenalty
+	if confidence < 0 {
+		confidence = 0
+	}
+
+	return confidence
+}
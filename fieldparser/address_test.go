@@ -0,0 +1,50 @@
+package fieldparser
+
+import "testing"
+
+func TestParseAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		city    string
+		want    Address
+	}{
+		{
+			address: "123456, г. Москва, ул. Ленина, д. 5, кв. 12",
+			want: Address{Region: "Москва", City: "Москва", Street: "ул. Ленина", House: "5",
+				Apartment: "12", PostCode: "123456", Confidence: 100},
+		},
+		{
+			address: "Геленджик, ул. Ленина 5-12",
+			want: Address{City: "Геленджик", Street: "ул. Ленина", House: "5", Apartment: "12",
+				Confidence: 90},
+		},
+		{
+			address: "Грозный, Гагарина ул. 5",
+			want:    Address{City: "Грозный", Street: "Гагарина ул.", House: "5", Confidence: 90},
+		},
+		{
+			address: "дер. Дербент, ул. Садовая 1",
+			want:    Address{City: "Дербент", Street: "ул. Садовая", House: "1", Confidence: 90},
+		},
+		{
+			address: "г Казань, пр-т Победы 10",
+			want:    Address{City: "Казань", Street: "пр-т Победы", House: "10", Confidence: 90},
+		},
+		{
+			address: "ул. Мира 3",
+			city:    "Грозный",
+			want:    Address{City: "Грозный", Street: "ул. Мира", House: "3", Confidence: 90},
+		},
+		{
+			address: "ул. Мира 3",
+			city:    "г.Сочи",
+			want:    Address{City: "Сочи", Street: "ул. Мира", House: "3", Confidence: 90},
+		},
+	}
+
+	for _, test := range tests {
+		if got := ParseAddress(test.address, test.city); got != test.want {
+			t.Errorf("ParseAddress(%q, %q) = %+v, want %+v", test.address, test.city, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package fieldparser
+
+import "unicode"
+
+// character classes of inscriptions
+const (
+	CharClassCyrillic    = "cyrillic"
+	CharClassLatin       = "latin"
+	CharClassGreek       = "greek"
+	CharClassDigit       = "digit"
+	CharClassSpace       = "space"
+	CharClassPunctuation = "punctuation"
+	CharClassEmoji       = "emoji"
+	CharClassSymbol      = "symbol"
+	CharClassOther       = "other"
+)
+
+var CharClasses = []string{
+	CharClassCyrillic, CharClassLatin, CharClassGreek, CharClassDigit, CharClassSpace,
+	CharClassPunctuation, CharClassEmoji, CharClassSymbol, CharClassOther,
+}
+
+// CharClass returns character class of a rune. Emoji are pictographs of supplementary planes
+// with their joiners and variation selectors, other pictographic characters (♥, ∞) are symbols
+func CharClass(char rune) string {
+	switch {
+	case unicode.Is(unicode.Cyrillic, char):
+		return CharClassCyrillic
+	case unicode.Is(unicode.Latin, char):
+		return CharClassLatin
+	case unicode.Is(unicode.Greek, char):
+		return CharClassGreek
+	case unicode.IsDigit(char):
+		return CharClassDigit
+	case unicode.IsSpace(char):
+		return CharClassSpace
+	case char >= 0x1F000 || char == 0x200D || char == 0xFE0F:
+		return CharClassEmoji
+	case unicode.IsPunct(char):
+		return CharClassPunctuation
+	case unicode.IsSymbol(char):
+		return CharClassSymbol
+	}
+	return CharClassOther
+}
+
+// IsSpecialCharClass reports whether a class is of characters other than letters, digits
+// and spaces
+func IsSpecialCharClass(class string) bool {
+	switch class {
+	case CharClassCyrillic, CharClassLatin, CharClassGreek, CharClassDigit, CharClassSpace:
+		return false
+	}
+	return true
+}
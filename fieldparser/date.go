@@ -0,0 +1,7 @@
+package fieldparser
+
+// IsBatchDate reports whether date belongs to a regular batch sheet and not to
+// "НАЛИЧИЕ" or "Срочные заказы" sheets which are stored with zero month
+func IsBatchDate(date string) bool {
+	return len(date) == 10 && date[5:7] != "00"
+}
@@ -0,0 +1,72 @@
+package fieldparser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+)
+
+var (
+	timeOfDayRegex  = regexp.MustCompile(`(\d{1,2})(?:\s*([:.\-])\s*(\d{2}))?`)
+	hourMarkerRegex = regexp.MustCompile(`(?:^|[^\p{L}])(?:с|до|после|от|в|к)\s*\d|\d\s*ч`)
+	wordRegex       = regexp.MustCompile(`[\p{L}\d]+`)
+)
+
+// ParseDeliveryMethod maps free text of "Способ доставки" column to one of delivery methods
+// using synonym table from settings. Synonyms of up to three letters ("сам", "пр") must match
+// a whole word, longer ones are looked up as substrings
+func ParseDeliveryMethod(deliveryType string, methods []config.DeliveryMethodSynonyms) string {
+	deliveryType = strings.ToLower(strings.TrimSpace(deliveryType))
+	if deliveryType == "" {
+		return ""
+	}
+
+	words := make(map[string]bool)
+	for _, word := range wordRegex.FindAllString(deliveryType, -1) {
+		words[word] = true
+	}
+
+	for _, method := range methods {
+		for _, synonym := range method.Synonyms {
+			synonym = strings.ToLower(synonym)
+			if len([]rune(synonym)) <= 3 && words[synonym] {
+				return method.Method
+			}
+			if len([]rune(synonym)) > 3 && strings.Contains(deliveryType, synonym) {
+				return method.Method
+			}
+		}
+	}
+
+	return config.DeliveryMethodUnknown
+}
+
+// ParseTimeOfDay parses free text time ("15", "15:30", "с 15.00", "до 18-00") into "HH:MM".
+// Minutes separated by a dot or a dash are accepted only after an hour marker ("с", "до", ...)
+// or before "ч". Empty string is returned when no valid time is found
+func ParseTimeOfDay(value string) string {
+	match := timeOfDayRegex.FindStringSubmatch(value)
+	if match == nil {
+		return ""
+	}
+
+	// "12.06" is rather a date than a time unless it is written like "с 12.06" or "12.06 ч"
+	if match[2] != "" && match[2] != ":" && !hourMarkerRegex.MatchString(strings.ToLower(value)) {
+		return ""
+	}
+
+	hours, _ := strconv.Atoi(match[1])
+	minutes := 0
+	if match[3] != "" {
+		minutes, _ = strconv.Atoi(match[3])
+	}
+
+	if hours > 24 || minutes > 59 || (hours == 24 && minutes > 0) {
+		return ""
+	}
+
+	return fmt.Sprintf("%02d:%02d", hours, minutes)
+}
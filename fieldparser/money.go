@@ -0,0 +1,148 @@
+package fieldparser
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	MoneyStatusEmpty      = "empty"
+	MoneyStatusOK         = "ok"
+	MoneyStatusExpression = "expression"
+	MoneyStatusRange      = "range"
+	MoneyStatusExtracted  = "extracted"
+	MoneyStatusInvalid    = "invalid"
+)
+
+var (
+	currencyReplacer = strings.NewReplacer(
+		"₽", " ", "руб.", " ", "руб", " ", "rub", " ", "р.", " ",
+		"\u00a0", " ", "\u202f", " ", "\u2009", " ", "–", "-", "—", "-", "×", "*",
+	)
+	thousandsRegex    = regexp.MustCompile(`\b\d{1,3}(?: \d{3})+\b`)
+	dotThousandsRegex = regexp.MustCompile(`\b\d{1,3}(?:\.\d{3})+\b`)
+	commaRegex        = regexp.MustCompile(`(\d),(\d+)`)
+	rubleSuffix       = regexp.MustCompile(`(\d)\s*р(\s|$)`)
+	numberRegex       = regexp.MustCompile(`^\d+(\.\d+)?$`)
+	expressionRegex   = regexp.MustCompile(`^\d+(\.\d+)?(\s*[-+*]\s*\d+(\.\d+)?)+$`)
+	rangeRegex        = regexp.MustCompile(`^(?:от\s*)?(\d+(?:\.\d+)?)\s*(?:-|до)\s*(\d+(?:\.\d+)?)$`)
+	anyNumberRegex    = regexp.MustCompile(`\d+(\.\d+)?`)
+)
+
+// ParseMoney parses a free text amount of money ("3 500 ₽", "3.500", "2500+300", "2500-3000",
+// "оплачено 3000", "1500,50") into kopecks. Status tells how the value was obtained:
+// ranges are stored by their lower bound, "extracted" means the only number found in text
+func ParseMoney(raw string) (kopecks int, status string) {
+	value := strings.ToLower(strings.TrimSpace(raw))
+	if value == "" {
+		return 0, MoneyStatusEmpty
+	}
+
+	value = currencyReplacer.Replace(value)
+	value = rubleSuffix.ReplaceAllString(value, "$1 ")
+	value = strings.Join(strings.Fields(value), " ")
+
+	value = thousandsRegex.ReplaceAllStringFunc(value, func(match string) string {
+		return strings.ReplaceAll(match, " ", "")
+	})
+
+	// a dot followed by exactly three digits separates thousands ("3.500"), not kopecks
+	value = dotThousandsRegex.ReplaceAllStringFunc(value, func(match string) string {
+		return strings.ReplaceAll(match, ".", "")
+	})
+
+	value = commaRegex.ReplaceAllStringFunc(value, func(match string) string {
+		parts := strings.SplitN(match, ",", 2)
+		if len(parts[1]) == 3 {
+			return parts[0] + parts[1]
+		}
+		return parts[0] + "." + parts[1]
+	})
+
+	switch {
+	case numberRegex.MatchString(value):
+		return toKopecks(parseFloat(value)), MoneyStatusOK
+
+	case rangeRegex.MatchString(value):
+		match := rangeRegex.FindStringSubmatch(value)
+		lower, upper := parseFloat(match[1]), parseFloat(match[2])
+		if strings.Contains(value, "-") && upper < lower {
+			// "2500-300" is a discount, not a range
+			return toKopecks(lower - upper), MoneyStatusExpression
+		}
+		return toKopecks(math.Min(lower, upper)), MoneyStatusRange
+
+	case expressionRegex.MatchString(value):
+		result, ok := evaluateExpression(value)
+		if !ok {
+			return 0, MoneyStatusInvalid
+		}
+		return toKopecks(result), MoneyStatusExpression
+	}
+
+	numbers := anyNumberRegex.FindAllString(value, -1)
+	if len(numbers) == 1 {
+		return toKopecks(parseFloat(numbers[0])), MoneyStatusExtracted
+	}
+
+	return 0, MoneyStatusInvalid
+}
+
+// evaluateExpression computes "+", "-" and "*" expression respecting multiplication precedence
+func evaluateExpression(expression string) (float64, bool) {
+	expression = strings.ReplaceAll(expression, " ", "")
+
+	var terms []float64
+	sign := 1.0
+	start := 0
+
+	for i := 0; i <= len(expression); i++ {
+		if i < len(expression) && expression[i] != '+' && expression[i] != '-' {
+			continue
+		}
+
+		term := 1.0
+		for _, factor := range strings.Split(expression[start:i], "*") {
+			number, err := strconv.ParseFloat(factor, 64)
+			if err != nil {
+				return 0, false
+			}
+			term *= number
+		}
+		terms = append(terms, sign*term)
+
+		if i < len(expression) && expression[i] == '-' {
+			sign = -1
+		} else {
+			sign = 1
+		}
+		start = i + 1
+	}
+
+	result := 0.0
+	for _, term := range terms {
+		result += term
+	}
+
+	return result, result >= 0
+}
+
+// FormatKopecks formats kopecks as rubles, omitting zero kopecks ("1500", "1500.50")
+func FormatKopecks(kopecks int) string {
+	if kopecks%100 == 0 {
+		return strconv.Itoa(kopecks / 100)
+	}
+	return fmt.Sprintf("%d.%02d", kopecks/100, kopecks%100)
+}
+
+func parseFloat(value string) float64 {
+	number, _ := strconv.ParseFloat(value, 64)
+	return number
+}
+
+func toKopecks(rubles float64) int {
+	return int(math.Round(rubles * 100))
+}
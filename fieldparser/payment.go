@@ -0,0 +1,100 @@
+package fieldparser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	PaymentStatusPaid    = "paid"
+	PaymentStatusPartial = "partial"
+	PaymentStatusUnpaid  = "unpaid"
+	PaymentStatusUnknown = "unknown"
+)
+
+var (
+	unpaidKeywords  = keywordsRegex(`нет|не|no|неоплач\p{L}*|жд[её]\p{L}*|ожида\p{L}*`)
+	notFully        = keywordsRegex(`не\s+(?:полностью|вс\p{L}*|до\s+конца)`)
+	cashOnDelivery  = keywordsRegex(`при\s+получени\p{L}*|налож\p{L}*|cod`)
+	partialKeywords = keywordsRegex(`предоплат\p{L}*|частич\p{L}*|аванс\p{L}*|half|половин\p{L}*`)
+	paidKeywords    = keywordsRegex(`да|yes|оплачен\p{L}*|оплата|оплатил\p{L}*|полностью|full|ok|ок`)
+	paidSymbols     = "+✓✅"
+	outOfRegex      = regexp.MustCompile(`(\d[\d .,]*)\s*(?:₽|руб\.?|р\.?)?\s+из\s+(\d[\d .,]*)`)
+	percentRegex    = regexp.MustCompile(`(\d{1,3})\s*%`)
+	paymentDate     = regexp.MustCompile(`^\d{1,2}\.\d{1,2}(\.\d{2,4})?$`)
+)
+
+// ParsePayment classifies free text of "Оплата" column ("да", "предоплата 50%", "нет",
+// "1500 из 3000", "при получении", a payment date or an amount) into a payment status and
+// extracts paid amount in kopecks. sumKopecks is the order sum used to resolve percentages
+// and full payments
+func ParsePayment(payment string, sumKopecks int) (status string, paidKopecks int) {
+	value := strings.ToLower(strings.TrimSpace(payment))
+
+	if value == "" {
+		if sumKopecks > 0 {
+			return PaymentStatusUnpaid, 0
+		}
+		return PaymentStatusUnknown, 0
+	}
+
+	// "оплачено не полностью" is a partial payment despite the negation
+	partial := notFully.MatchString(value)
+
+	// cash on delivery ("оплата при получении", "наложенный платёж") is not paid yet
+	unpaid := value == "-" || unpaidKeywords.MatchString(value) || cashOnDelivery.MatchString(value)
+	if unpaid && !partial {
+		return PaymentStatusUnpaid, 0
+	}
+
+	// "1500 из 3000" is a partial payment of 1500
+	if match := outOfRegex.FindStringSubmatch(value); match != nil {
+		paid, _ := ParseMoney(match[1])
+		total, _ := ParseMoney(match[2])
+		if paid > 0 && total > 0 {
+			if paid >= total {
+				return PaymentStatusPaid, paid
+			}
+			return PaymentStatusPartial, paid
+		}
+	}
+
+	if partial || partialKeywords.MatchString(value) || percentRegex.MatchString(value) {
+		if match := percentRegex.FindStringSubmatch(value); match != nil {
+			percent, _ := strconv.Atoi(match[1])
+			if percent >= 100 {
+				return PaymentStatusPaid, sumKopecks
+			}
+			return PaymentStatusPartial, sumKopecks * percent / 100
+		}
+		amount, moneyStatus := ParseMoney(value)
+		if moneyStatus == MoneyStatusInvalid {
+			amount = 0
+		}
+		return PaymentStatusPartial, amount
+	}
+
+	if paymentDate.MatchString(value) {
+		return PaymentStatusPaid, sumKopecks
+	}
+
+	if amount, moneyStatus := ParseMoney(value); moneyStatus != MoneyStatusInvalid && amount > 0 {
+		if sumKopecks > 0 && amount < sumKopecks {
+			return PaymentStatusPartial, amount
+		}
+		return PaymentStatusPaid, amount
+	}
+
+	if paidKeywords.MatchString(value) || strings.ContainsAny(value, paidSymbols) {
+		return PaymentStatusPaid, sumKopecks
+	}
+
+	return PaymentStatusUnknown, 0
+}
+
+// keywordsRegex matches any of alternatives as a separate word, so that "да" is not found
+// in "когда" and "не" in "неделя"
+func keywordsRegex(alternatives string) *regexp.Regexp {
+	return regexp.MustCompile(`(?:^|[^\p{L}\p{N}])(?:` + alternatives + `)(?:$|[^\p{L}\p{N}])`)
+}
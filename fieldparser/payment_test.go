@@ -0,0 +1,44 @@
+package fieldparser
+
+import "testing"
+
+func TestParsePayment(t *testing.T) {
+	const sum = 300000
+
+	tests := []struct {
+		payment    string
+		wantStatus string
+		wantPaid   int
+	}{
+		{"", PaymentStatusUnpaid, 0},
+		{"да", PaymentStatusPaid, sum},
+		{"Да!", PaymentStatusPaid, sum},
+		{"+", PaymentStatusPaid, sum},
+		{"12.06", PaymentStatusPaid, sum},
+		{"3000", PaymentStatusPaid, sum},
+		{"1500", PaymentStatusPartial, 150000},
+		{"нет", PaymentStatusUnpaid, 0},
+		{"не оплачено", PaymentStatusUnpaid, 0},
+		{"неоплачено", PaymentStatusUnpaid, 0},
+		{"ждём", PaymentStatusUnpaid, 0},
+		{"оплата при получении", PaymentStatusUnpaid, 0},
+		{"наложенный платёж", PaymentStatusUnpaid, 0},
+		{"когда заберет", PaymentStatusUnknown, 0},
+		{"через неделю", PaymentStatusUnknown, 0},
+		{"предоплата 50%", PaymentStatusPartial, 150000},
+		{"предоплата 1000", PaymentStatusPartial, 100000},
+		{"Оплачено 1500 из 3000", PaymentStatusPartial, 150000},
+		{"оплачено 3 000 из 3 000", PaymentStatusPaid, sum},
+		{"оплачено не полностью", PaymentStatusPartial, 0},
+		{"оплатили не всю сумму", PaymentStatusPartial, 0},
+		{"не полностью, 1000 из 3000", PaymentStatusPartial, 100000},
+	}
+
+	for _, test := range tests {
+		status, paid := ParsePayment(test.payment, sum)
+		if status != test.wantStatus || paid != test.wantPaid {
+			t.Errorf("ParsePayment(%q) = %s, %d, want %s, %d",
+				test.payment, status, paid, test.wantStatus, test.wantPaid)
+		}
+	}
+}
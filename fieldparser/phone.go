@@ -0,0 +1,79 @@
+package fieldparser
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	russianCountryCode = "7"
+	minPhoneDigits     = 8
+	maxPhoneDigits     = 15
+)
+
+// NormalizePhone converts Russian and international phone numbers typed in any format
+// ("8 (916) 123-45-67", "+7916...", "9161234567", "00375...") to E.164 ("+79161234567").
+// If a cell contains several numbers, the first one is used. ok is false if the value is not
+// empty but can't be parsed as a phone number
+func NormalizePhone(phone string) (normalized string, ok bool) {
+	phone = strings.TrimSpace(phone)
+	if phone == "" {
+		return "", true
+	}
+
+	if idx := strings.IndexAny(phone, ",;/\n"); idx > 0 {
+		phone = phone[:idx]
+	}
+
+	hasPlus := strings.HasPrefix(strings.TrimLeft(phone, " ("), "+")
+
+	var builder strings.Builder
+	for _, char := range phone {
+		if unicode.IsDigit(char) {
+			builder.WriteRune(char)
+		}
+	}
+	digits := builder.String()
+
+	switch {
+	case hasPlus:
+	case strings.HasPrefix(digits, "00"):
+		digits = digits[2:]
+	case strings.HasPrefix(digits, "810") && len(digits) > 11:
+		digits = digits[3:]
+	case len(digits) == 11 && (digits[0] == '8' || digits[0] == '7'):
+		digits = russianCountryCode + digits[1:]
+	case len(digits) == 10 && strings.ContainsRune("3489", rune(digits[0])):
+		digits = russianCountryCode + digits
+	case len(digits) <= 11:
+		return "", false
+	}
+
+	if len(digits) < minPhoneDigits || len(digits) > maxPhoneDigits || digits[0] == '0' {
+		return "", false
+	}
+	// numbering zone 7 (Russia and Kazakhstan) always has 10 digits after the country code
+	if strings.HasPrefix(digits, russianCountryCode) && len(digits) != 11 {
+		return "", false
+	}
+
+	return "+" + digits, true
+}
+
+// PhoneDigits returns only digits of a partially typed phone number, dropping Russian trunk
+// prefix "8" or country code "7" so the rest can be matched against normalized phones
+func PhoneDigits(phone string) string {
+	var builder strings.Builder
+	for _, char := range phone {
+		if unicode.IsDigit(char) {
+			builder.WriteRune(char)
+		}
+	}
+	digits := builder.String()
+
+	if len(digits) == 11 && (digits[0] == '8' || digits[0] == '7') {
+		digits = digits[1:]
+	}
+
+	return digits
+}
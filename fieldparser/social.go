@@ -0,0 +1,145 @@
+package fieldparser
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	SocialNetworkVK        = "vk"
+	SocialNetworkInstagram = "instagram"
+	SocialNetworkTelegram  = "telegram"
+	SocialNetworkOK        = "ok"
+	SocialNetworkFacebook  = "facebook"
+)
+
+var (
+	socialNetworkHosts = map[string]string{
+		"vk.com":        SocialNetworkVK,
+		"vk.ru":         SocialNetworkVK,
+		"vk.me":         SocialNetworkVK,
+		"vkontakte.ru":  SocialNetworkVK,
+		"instagram.com": SocialNetworkInstagram,
+		"instagr.am":    SocialNetworkInstagram,
+		"t.me":          SocialNetworkTelegram,
+		"telegram.me":   SocialNetworkTelegram,
+		"telegram.dog":  SocialNetworkTelegram,
+		"ok.ru":         SocialNetworkOK,
+		"facebook.com":  SocialNetworkFacebook,
+		"fb.com":        SocialNetworkFacebook,
+	}
+	// socialNetworkNames maps lowercase prefixes of "Соцсеть" values to networks. Telegram
+	// needs its full name as "тел" may stand for "телефон"
+	socialNetworkNames = map[string]string{
+		"vk":       SocialNetworkVK,
+		"вк":       SocialNetworkVK,
+		"вко":      SocialNetworkVK,
+		"ins":      SocialNetworkInstagram,
+		"инс":      SocialNetworkInstagram,
+		"ig":       SocialNetworkInstagram,
+		"tg":       SocialNetworkTelegram,
+		"тг":       SocialNetworkTelegram,
+		"telegram": SocialNetworkTelegram,
+		"телеграм": SocialNetworkTelegram,
+		"ok":       SocialNetworkOK,
+		"ок":       SocialNetworkOK,
+		"одн":      SocialNetworkOK,
+		"fb":       SocialNetworkFacebook,
+		"fac":      SocialNetworkFacebook,
+		"фей":      SocialNetworkFacebook,
+	}
+	socialHandleRegex = regexp.MustCompile(`^[a-z0-9_.]{2,}$`)
+	vkDialogRegex     = regexp.MustCompile(`^(?:write|im)(\d+)$`)
+)
+
+// ParseSocialLink parses a customer link in any form (full URL, mobile URL, "vk.com/id123",
+// "@handle" or bare handle) into network and canonical lowercase handle. socials ("Соцсеть"
+// column) is used when the link itself doesn't say which network it belongs to. Empty values
+// are returned if the link can't be parsed
+func ParseSocialLink(link, socials string) (network, handle string) {
+	link = strings.ToLower(strings.TrimSpace(link))
+	if link == "" {
+		return "", ""
+	}
+
+	if !strings.Contains(link, "/") {
+		network = ParseSocialNetwork(socials)
+		handle = strings.TrimPrefix(link, "@")
+		if network == SocialNetworkVK && isDigits(handle) {
+			handle = "id" + handle
+		}
+		if network == "" || !socialHandleRegex.MatchString(handle) {
+			return "", ""
+		}
+		return network, handle
+	}
+
+	if !strings.Contains(link, "://") {
+		link = "https://" + link
+	}
+
+	parsedURL, err := url.Parse(link)
+	if err != nil {
+		return "", ""
+	}
+
+	host := strings.TrimPrefix(strings.TrimPrefix(parsedURL.Hostname(), "www."), "m.")
+	network, exists := socialNetworkHosts[host]
+	if !exists {
+		return "", ""
+	}
+
+	segments := strings.FieldsFunc(parsedURL.Path, func(r rune) bool { return r == '/' })
+	if network == SocialNetworkInstagram && len(segments) > 1 && segments[0] == "_u" {
+		segments = segments[1:]
+	}
+
+	switch {
+	case network == SocialNetworkVK && parsedURL.Query().Get("sel") != "":
+		// links to a dialog, eg "vk.com/im?sel=123" or "vk.com/gim1?sel=123"
+		handle = "id" + parsedURL.Query().Get("sel")
+	case network == SocialNetworkVK && parsedURL.Query().Get("id") != "":
+		// links like "vk.com/profile.php?id=123"
+		handle = "id" + parsedURL.Query().Get("id")
+	case len(segments) > 0:
+		handle = segments[0]
+	}
+
+	if match := vkDialogRegex.FindStringSubmatch(handle); network == SocialNetworkVK && match != nil {
+		handle = "id" + match[1]
+	}
+
+	handle = strings.TrimPrefix(handle, "@")
+	if !socialHandleRegex.MatchString(handle) {
+		return "", ""
+	}
+
+	return network, handle
+}
+
+// ParseSocialNetwork maps free text of "Соцсеть" column ("вк", "VK", "инста", "тг"...) to
+// a network
+func ParseSocialNetwork(socials string) string {
+	socials = strings.ToLower(strings.TrimSpace(socials))
+
+	for prefix, network := range socialNetworkNames {
+		if strings.HasPrefix(socials, prefix) {
+			return network
+		}
+	}
+
+	return ""
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, char := range s {
+		if char < '0' || char > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,32 @@
+package fieldparser
+
+import "testing"
+
+func TestParseSocialLink(t *testing.T) {
+	tests := []struct {
+		link        string
+		socials     string
+		wantNetwork string
+		wantHandle  string
+	}{
+		{"https://vk.com/id123", "", SocialNetworkVK, "id123"},
+		{"m.vk.com/im?sel=123", "", SocialNetworkVK, "id123"},
+		{"123", "вк", SocialNetworkVK, "id123"},
+		{"@Ivanova", "инста", SocialNetworkInstagram, "ivanova"},
+		{"https://t.me/ivanova", "", SocialNetworkTelegram, "ivanova"},
+		{"ivanova", "тг", SocialNetworkTelegram, "ivanova"},
+		{"ivanova", "Телеграм", SocialNetworkTelegram, "ivanova"},
+		{"ivanova", "телефон", "", ""},
+		{"ivanova", "тел.", "", ""},
+		{"ivanova", "", "", ""},
+		{"https://example.com/ivanova", "", "", ""},
+	}
+
+	for _, test := range tests {
+		network, handle := ParseSocialLink(test.link, test.socials)
+		if network != test.wantNetwork || handle != test.wantHandle {
+			t.Errorf("ParseSocialLink(%q, %q) = %q, %q, want %q, %q",
+				test.link, test.socials, network, handle, test.wantNetwork, test.wantHandle)
+		}
+	}
+}
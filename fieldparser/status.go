@@ -0,0 +1,62 @@
+package fieldparser
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+)
+
+// ParseRowStatus maps a row background colour ("#rrggbb") to a status from palette. The
+// closest palette colour within config.StatusColorTolerance wins. Rows without fill have no
+// status, colours which are not in palette get "unknown"
+func ParseRowStatus(color string, palette []config.StatusColors) string {
+	rgb, ok := parseHexColor(color)
+	if !ok {
+		return ""
+	}
+
+	status := config.StatusUnknown
+	bestDistance := float64(config.StatusColorTolerance)
+
+	for _, statusColors := range palette {
+		for _, paletteColor := range statusColors.Colors {
+			paletteRGB, ok := parseHexColor(paletteColor)
+			if !ok {
+				continue
+			}
+
+			distance := math.Sqrt(
+				math.Pow(rgb[0]-paletteRGB[0], 2) +
+					math.Pow(rgb[1]-paletteRGB[1], 2) +
+					math.Pow(rgb[2]-paletteRGB[2], 2))
+
+			if distance <= bestDistance {
+				bestDistance = distance
+				status = statusColors.Status
+			}
+		}
+	}
+
+	return status
+}
+
+func parseHexColor(color string) ([3]float64, bool) {
+	var rgb [3]float64
+
+	color = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(color)), "#")
+	if len(color) != 6 {
+		return rgb, false
+	}
+
+	for i := 0; i < 3; i++ {
+		value, err := strconv.ParseUint(color[i*2:i*2+2], 16, 8)
+		if err != nil {
+			return rgb, false
+		}
+		rgb[i] = float64(value)
+	}
+
+	return rgb, true
+}
@@ -0,0 +1,199 @@
+package issueshandler
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/db"
+	"github.com/crush-on-anechka/ktn_stats/fieldparser"
+)
+
+// names of rules checking engraving constraints from settings
+const (
+	RuleEngravingLineLength = "engraving_line_length"
+	RuleEngravingLines      = "engraving_lines"
+	RuleEngravingCharset    = "engraving_charset"
+)
+
+var engravingRuleNames = []string{RuleEngravingLineLength, RuleEngravingLines, RuleEngravingCharset}
+
+// checkEngravingLineLength reports inscription lines longer than constraints allow
+func checkEngravingLineLength(order db.Data) (string, bool) {
+	return checkEngraving(order, func(constraint config.EngravingConstraint, lines []string) string {
+		if constraint.MaxLineLength == 0 {
+			return ""
+		}
+
+		var problems []string
+		for i, line := range lines {
+			if length := utf8.RuneCountInString(line); length > constraint.MaxLineLength {
+				problems = append(problems, fmt.Sprintf("line %d is %d characters, max %d",
+					i+1, length, constraint.MaxLineLength))
+			}
+		}
+		return strings.Join(problems, ", ")
+	})
+}
+
+// checkEngravingLines reports inscriptions with more lines than constraints allow
+func checkEngravingLines(order db.Data) (string, bool) {
+	return checkEngraving(order, func(constraint config.EngravingConstraint, lines []string) string {
+		if constraint.MaxLines == 0 || len(lines) <= constraint.MaxLines {
+			return ""
+		}
+		return fmt.Sprintf("%d lines, max %d", len(lines), constraint.MaxLines)
+	})
+}
+
+// checkEngravingCharset reports characters which aren't allowed by constraints
+func checkEngravingCharset(order db.Data) (string, bool) {
+	return checkEngraving(order, func(constraint config.EngravingConstraint, lines []string) string {
+		if len(constraint.AllowedClasses) == 0 {
+			return ""
+		}
+
+		var unsupported []string
+		for _, line := range lines {
+			for _, char := range line {
+				if slices.Contains(constraint.AllowedClasses, fieldparser.CharClass(char)) ||
+					strings.ContainsRune(constraint.AllowedChars, char) ||
+					slices.Contains(unsupported, string(char)) {
+					continue
+				}
+				unsupported = append(unsupported, string(char))
+			}
+		}
+
+		if len(unsupported) == 0 {
+			return ""
+		}
+		return fmt.Sprintf("unsupported characters %q", strings.Join(unsupported, ""))
+	})
+}
+
+// checkEngraving runs check of every matching constraint over non-empty inscription fields
+// of engraved types split into lines and joins problems as "field: problem; ..."
+func checkEngraving(
+	order db.Data, check func(constraint config.EngravingConstraint, lines []string) string,
+) (string, bool) {
+
+	if !slices.Contains(config.AppSettings.EngravedTypes, order.Type) {
+		return "", false
+	}
+
+	var problems []string
+
+	for _, field := range db.InscriptionFieldnames() {
+		value := strings.TrimSpace(fieldValue(order, field))
+		if value == "" {
+			continue
+		}
+
+		var lines []string
+		for _, line := range strings.FieldsFunc(value, func(r rune) bool { return r == '\n' || r == '\r' }) {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+
+		for _, constraint := range config.AppSettings.EngravingConstraints {
+			if !constraintMatches(constraint, order, field) {
+				continue
+			}
+			if problem := check(constraint, lines); problem != "" && !slices.Contains(problems, field+": "+problem) {
+				problems = append(problems, field+": "+problem)
+			}
+		}
+	}
+
+	if len(problems) == 0 {
+		return "", false
+	}
+	return strings.Join(problems, "; "), true
+}
+
+func constraintMatches(constraint config.EngravingConstraint, order db.Data, field string) bool {
+	if constraint.Field != "" && constraint.Field != field {
+		return false
+	}
+	if constraint.Type != "" && constraint.Type != order.Type {
+		return false
+	}
+	return constraint.SubtypePattern == "" || likeMatch(constraint.SubtypePattern, order.Subtype)
+}
+
+// likeMatch matches a value with SQL LIKE pattern ("%" - any characters, "_" - one character)
+// ignoring case
+func likeMatch(pattern, value string) bool {
+	expression := regexp.QuoteMeta(strings.ToLower(pattern))
+	expression = strings.ReplaceAll(strings.ReplaceAll(expression, "%", ".*"), "_", ".")
+
+	matched, err := regexp.MatchString("(?s)^"+expression+"$", strings.ToLower(value))
+	return err == nil && matched
+}
+
+// fieldValue returns value of a Data field by its sheet field name
+func fieldValue(order db.Data, fieldname string) string {
+	v := reflect.ValueOf(order)
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("fieldname") == fieldname {
+			return v.Field(i).String()
+		}
+	}
+	return ""
+}
+
+// GetEngravingViolations returns engraving constraint issues of a batch
+func (handler *IssuesHandler) GetEngravingViolations(date string) ([]db.Issue, error) {
+	issues, err := handler.storage.GetIssues(date, date, "", "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch issues: %w", err)
+	}
+
+	violations := []db.Issue{}
+	for _, issue := range issues {
+		if slices.Contains(engravingRuleNames, issue.Rule) {
+			violations = append(violations, issue)
+		}
+	}
+
+	return violations, nil
+}
+
+// EngravingSummary returns engraving constraint violations of given dates one per line,
+// at most config.EngravingReportLimit of them. Empty string is returned if there are none
+func (handler *IssuesHandler) EngravingSummary(dates []string) (string, error) {
+	var violations []db.Issue
+
+	for _, date := range dates {
+		dateViolations, err := handler.GetEngravingViolations(date)
+		if err != nil {
+			return "", err
+		}
+		violations = append(violations, dateViolations...)
+	}
+
+	if len(violations) == 0 {
+		return "", nil
+	}
+
+	var builder strings.Builder
+	builder.WriteString("Engraving constraints violations:")
+
+	for i, violation := range violations {
+		if i == config.EngravingReportLimit {
+			builder.WriteString(fmt.Sprintf("\n- and %d more", len(violations)-config.EngravingReportLimit))
+			break
+		}
+		builder.WriteString(fmt.Sprintf("\n- %s row %d: %s", violation.Date, violation.RowNumber, violation.Message))
+	}
+
+	return builder.String(), nil
+}
@@ -0,0 +1,104 @@
+package issueshandler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/db"
+)
+
+type IssuesHandler struct {
+	storage *db.SqliteDB
+	rules   []Rule
+}
+
+func New(storage *db.SqliteDB) *IssuesHandler {
+	return &IssuesHandler{storage: storage, rules: builtinRules}
+}
+
+// CheckOrdersByDate runs all rules over stored orders of a sheet and replaces its issues
+func (handler *IssuesHandler) CheckOrdersByDate(date string) error {
+	orders, err := handler.storage.GetOrdersByDate(date)
+	if err != nil {
+		return fmt.Errorf("failed to fetch orders from db: %w", err)
+	}
+
+	var issues []db.Issue
+
+	for _, order := range orders {
+		for _, rule := range handler.rules {
+			message, failed := rule.Check(order)
+			if !failed {
+				continue
+			}
+
+			issues = append(issues, db.Issue{
+				Date:      order.Date,
+				RowNumber: order.RowNumber,
+				Rule:      rule.Name,
+				Severity:  rule.Severity,
+				Message:   message,
+			})
+		}
+	}
+
+	tx, err := handler.storage.BeginTransaction()
+	if err != nil {
+		return err
+	}
+
+	if err = handler.storage.ReplaceIssuesByDateWithTx(tx, date, issues); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to store issues for %s: %w", date, err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Summary returns a short text report of issues found in given dates, one line per date
+// with counts by rule, at most config.IssuesSummaryDates of the latest dates. Empty string
+// is returned if there are no issues
+func (handler *IssuesHandler) Summary(dates []string) (string, error) {
+	counts, err := handler.storage.GetIssueCounts(dates)
+	if err != nil {
+		return "", fmt.Errorf("failed to count issues: %w", err)
+	}
+	if len(counts) == 0 {
+		return "", nil
+	}
+
+	rulesByDate := make(map[string][]string)
+	var sortedDates []string
+
+	for _, count := range counts {
+		if _, exists := rulesByDate[count.Date]; !exists {
+			sortedDates = append(sortedDates, count.Date)
+		}
+		rulesByDate[count.Date] = append(rulesByDate[count.Date],
+			fmt.Sprintf("%s (%s): %d", count.Rule, count.Severity, count.Count))
+	}
+
+	sort.Strings(sortedDates)
+
+	var builder strings.Builder
+	builder.WriteString("Data quality issues:")
+
+	// the most recent dates are shown, the rest are available at /admin/issues
+	if len(sortedDates) > config.IssuesSummaryDates {
+		builder.WriteString(fmt.Sprintf("\n- %d earlier dates, see /admin/issues",
+			len(sortedDates)-config.IssuesSummaryDates))
+		sortedDates = sortedDates[len(sortedDates)-config.IssuesSummaryDates:]
+	}
+
+	for _, date := range sortedDates {
+		builder.WriteString(fmt.Sprintf("\n%s: %s", date, strings.Join(rulesByDate[date], ", ")))
+	}
+
+	return builder.String(), nil
+}
@@ -0,0 +1,167 @@
+package issueshandler
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/db"
+	"github.com/crush-on-anechka/ktn_stats/fieldparser"
+)
+
+// Rule checks a single stored order. Check returns a message describing the problem and
+// true if the order violates the rule
+type Rule struct {
+	Name     string
+	Severity string
+	Check    func(order db.Data) (string, bool)
+}
+
+var builtinRules = []Rule{
+	{
+		Name:     "empty_inscription",
+		Severity: config.SeverityError,
+		Check:    checkEmptyInscription,
+	},
+	{
+		Name:     "invalid_sum",
+		Severity: config.SeverityError,
+		Check:    checkSum,
+	},
+	{
+		Name:     "ambiguous_sum",
+		Severity: config.SeverityInfo,
+		Check:    checkAmbiguousSum,
+	},
+	{
+		Name:     "invalid_email",
+		Severity: config.SeverityWarning,
+		Check:    checkEmail,
+	},
+	{
+		Name:     "invalid_phone",
+		Severity: config.SeverityWarning,
+		Check:    checkPhone,
+	},
+	{
+		Name:     "invalid_post_code",
+		Severity: config.SeverityWarning,
+		Check:    checkPostCode,
+	},
+	{
+		Name:     "post_code_mismatch",
+		Severity: config.SeverityWarning,
+		Check:    checkPostCodeMismatch,
+	},
+	{
+		Name:     "courier_without_address",
+		Severity: config.SeverityError,
+		Check:    checkCourierAddress,
+	},
+	{
+		Name:     RuleEngravingLineLength,
+		Severity: config.SeverityError,
+		Check:    checkEngravingLineLength,
+	},
+	{
+		Name:     RuleEngravingLines,
+		Severity: config.SeverityError,
+		Check:    checkEngravingLines,
+	},
+	{
+		Name:     RuleEngravingCharset,
+		Severity: config.SeverityWarning,
+		Check:    checkEngravingCharset,
+	},
+}
+
+func checkEmptyInscription(order db.Data) (string, bool) {
+	if !slices.Contains(config.AppSettings.EngravedTypes, order.Type) {
+		return "", false
+	}
+
+	v := reflect.ValueOf(order)
+	t := v.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		if config.FieldsWithInscription[t.Field(i).Name] && strings.TrimSpace(v.Field(i).String()) != "" {
+			return "", false
+		}
+	}
+
+	return fmt.Sprintf("no inscription for engraved type %s", order.Type), true
+}
+
+// checkSum skips merged rows and special sheets since their sum is stored in the first row
+// of an order
+func checkSum(order db.Data) (string, bool) {
+	if order.IsMerged || !fieldparser.IsBatchDate(order.Date) {
+		return "", false
+	}
+	if order.SumStatus == fieldparser.MoneyStatusInvalid {
+		return fmt.Sprintf("sum %q can't be parsed", order.SumRaw), true
+	}
+	if order.SumKopecks == 0 {
+		return "sum is zero", true
+	}
+	return "", false
+}
+
+// checkAmbiguousSum reports sums which were parsed from ranges, expressions or text
+func checkAmbiguousSum(order db.Data) (string, bool) {
+	switch order.SumStatus {
+	case fieldparser.MoneyStatusRange, fieldparser.MoneyStatusExpression, fieldparser.MoneyStatusExtracted:
+		return fmt.Sprintf("sum %q is parsed as %s (%s)",
+			order.SumRaw, fieldparser.FormatKopecks(order.SumKopecks), order.SumStatus), true
+	}
+	return "", false
+}
+
+func checkEmail(order db.Data) (string, bool) {
+	email := strings.TrimSpace(order.Email)
+	if email == "" {
+		return "", false
+	}
+
+	address, err := mail.ParseAddress(email)
+	if err != nil || !strings.Contains(address.Address[strings.LastIndex(address.Address, "@"):], ".") {
+		return fmt.Sprintf("invalid email %q", order.Email), true
+	}
+
+	return "", false
+}
+
+func checkPhone(order db.Data) (string, bool) {
+	if order.PhoneInvalid {
+		return fmt.Sprintf("phone %q can't be normalized", order.Phone), true
+	}
+	return "", false
+}
+
+func checkPostCode(order db.Data) (string, bool) {
+	if strings.TrimSpace(order.PostCode) == "" {
+		return "", false
+	}
+	if fieldparser.NormalizePostCode(order.PostCode) != strings.ReplaceAll(order.PostCode, " ", "") {
+		return fmt.Sprintf("post code %q is not 6 digits", order.PostCode), true
+	}
+	return "", false
+}
+
+func checkPostCodeMismatch(order db.Data) (string, bool) {
+	if order.PostCodeMismatch {
+		return fmt.Sprintf("post code %q differs from the one in address %q",
+			order.PostCode, order.AddressPostCode), true
+	}
+	return "", false
+}
+
+func checkCourierAddress(order db.Data) (string, bool) {
+	if order.DeliveryMethod == config.DeliveryMethodCourier && strings.TrimSpace(order.DeliveryAddress) == "" {
+		return "courier delivery without address", true
+	}
+	return "", false
+}
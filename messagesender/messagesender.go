@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
 )
 
 type Sender struct {
@@ -22,7 +25,19 @@ func New(botToken string, chatID int64) (*Sender, error) {
 
 }
 
+// SendMessageToTelegramBot sends a message, splitting it by lines into several messages if it
+// exceeds Telegram limit
 func (s *Sender) SendMessageToTelegramBot(message string) error {
+	for _, chunk := range splitMessage(message, config.TelegramMessageLimit) {
+		if err := s.sendMessage(chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Sender) sendMessage(message string) error {
 	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", s.botToken)
 
 	payload := map[string]interface{}{
@@ -55,3 +70,32 @@ func (s *Sender) SendMessageToTelegramBot(message string) error {
 
 	return nil
 }
+
+// splitMessage splits message into chunks of at most limit characters, breaking at line ends
+// where possible
+func splitMessage(message string, limit int) []string {
+	var chunks []string
+	var chunk []rune
+
+	for _, line := range strings.SplitAfter(message, "\n") {
+		runes := []rune(line)
+
+		if len(chunk)+len(runes) > limit && len(chunk) > 0 {
+			chunks = append(chunks, strings.TrimRight(string(chunk), "\n"))
+			chunk = nil
+		}
+
+		for len(runes) > limit {
+			chunks = append(chunks, string(runes[:limit]))
+			runes = runes[limit:]
+		}
+
+		chunk = append(chunk, runes...)
+	}
+
+	if len(chunk) > 0 || len(chunks) == 0 {
+		chunks = append(chunks, string(chunk))
+	}
+
+	return chunks
+}
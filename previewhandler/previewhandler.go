@@ -0,0 +1,249 @@
+package previewhandler
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	svg "github.com/ajstarks/svgo"
+	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/db"
+)
+
+// canvas and text layout in pixels. Text is set in monospace font, so a line is fitted to a
+// shape by its number of characters
+const (
+	canvasWidth = 600
+	margin      = 20
+	titleHeight = 30
+	edgeHeight  = 36
+	gap         = 16
+	labelHeight = 20
+	maxFontSize = 36
+	minFontSize = 6
+	charWidth   = 0.6
+	lineHeight  = 1.25
+	shapeStyle  = "fill:#eeeeee;stroke:#888888;stroke-width:2"
+	textStyle   = "font-family:monospace;text-anchor:middle;fill:#222222;font-size:%dpx"
+	labelStyle  = "font-family:sans-serif;font-size:12px;fill:#666666"
+	emptyStyle  = "font-family:sans-serif;text-anchor:middle;font-size:14px;fill:#aaaaaa"
+	emptyText   = "нет надписи"
+)
+
+type PreviewHandler struct {
+	storage *db.SqliteDB
+}
+
+func New(storage *db.SqliteDB) *PreviewHandler {
+	return &PreviewHandler{storage: storage}
+}
+
+// block is a shape with inscription lines fitted into a box within it
+type block struct {
+	label  string
+	lines  []string
+	height int
+	draw   func(canvas *svg.SVG, y int)
+	// text box relative to the block top
+	textX, textY, textWidth, textHeight int
+}
+
+// RenderPreview writes SVG preview of inscription fields of a stored row
+func (handler *PreviewHandler) RenderPreview(w io.Writer, date string, rowNumber int) error {
+	row, err := handler.storage.GetRow(date, rowNumber)
+	if err != nil {
+		return err
+	}
+
+	RenderInscription(w, row)
+	return nil
+}
+
+// RenderInscription draws inscription fields of a row onto a template of its product type:
+// ring band, round pendant, bracelet plate or a plain plate. "Верхний торец" and "Нижний торец"
+// are drawn as edge lines above and below the main shape, other non-empty inscription fields
+// follow it as plates
+func RenderInscription(w io.Writer, row db.Data) {
+	template, ok := config.PreviewTemplates[row.Type]
+	if !ok {
+		template = config.PreviewTemplatePlate
+	}
+
+	mainField, mainValue := mainInscription(template, row)
+
+	var blocks []block
+	if row.EdgeUpper != "" {
+		blocks = append(blocks, edgeBlock("Верхний торец", row.EdgeUpper))
+	}
+	blocks = append(blocks, templateBlock(template, mainField, mainValue))
+	if row.EdgeLower != "" {
+		blocks = append(blocks, edgeBlock("Нижний торец", row.EdgeLower))
+	}
+
+	for _, field := range inscriptionFields(row) {
+		if field.name != mainField && field.name != "Верхний торец" && field.name != "Нижний торец" {
+			blocks = append(blocks, templateBlock(config.PreviewTemplatePlate, field.name, field.value))
+		}
+	}
+
+	height := margin + titleHeight
+	for _, b := range blocks {
+		height += labelHeight + b.height + gap
+	}
+	height += margin - gap
+
+	canvas := svg.New(w)
+	canvas.Start(canvasWidth, height)
+	canvas.Title(fmt.Sprintf("%s, row %d", row.Date, row.RowNumber))
+	canvas.Rect(0, 0, canvasWidth, height, "fill:#ffffff")
+	title := strings.TrimSpace(fmt.Sprintf("%s, row %d: %s %s", row.Date, row.RowNumber, row.Type, row.Subtype))
+	canvas.Text(margin, margin+14, title, "font-family:sans-serif;font-size:14px;fill:#333333")
+
+	y := margin + titleHeight
+	for _, b := range blocks {
+		canvas.Text(margin, y+14, b.label, labelStyle)
+		y += labelHeight
+
+		b.draw(canvas, y)
+		drawLines(canvas, b.lines, b.textX, y+b.textY, b.textWidth, b.textHeight)
+
+		y += b.height + gap
+	}
+
+	canvas.End()
+}
+
+type field struct {
+	name  string
+	value string
+}
+
+// inscriptionFields returns non-empty inscription fields of a row in sheet order
+func inscriptionFields(row db.Data) []field {
+	values := map[string]string{
+		"Надпись":         row.Inscription,
+		"Нижний торец":    row.EdgeLower,
+		"Верхний торец":   row.EdgeUpper,
+		"Подвеска":        row.Pendant,
+		"Кольцо":          row.Ring,
+		"Браслет надпись": row.InscriptionBracelet,
+	}
+
+	var fields []field
+	for _, name := range db.InscriptionFieldnames() {
+		if value := strings.TrimSpace(values[name]); value != "" {
+			fields = append(fields, field{name: name, value: value})
+		}
+	}
+	return fields
+}
+
+// mainInscription returns field drawn on the main shape: the template's own field if filled,
+// "Надпись" otherwise
+func mainInscription(template string, row db.Data) (string, string) {
+	switch {
+	case template == config.PreviewTemplateRing && strings.TrimSpace(row.Ring) != "":
+		return "Кольцо", row.Ring
+	case template == config.PreviewTemplatePendant && strings.TrimSpace(row.Pendant) != "":
+		return "Подвеска", row.Pendant
+	case template == config.PreviewTemplateBracelet && strings.TrimSpace(row.InscriptionBracelet) != "":
+		return "Браслет надпись", row.InscriptionBracelet
+	}
+	return "Надпись", row.Inscription
+}
+
+func templateBlock(template, label, value string) block {
+	b := block{label: label, lines: splitLines(value)}
+
+	switch template {
+	case config.PreviewTemplateRing:
+		b.height = 100
+		b.draw = func(canvas *svg.SVG, y int) {
+			canvas.Roundrect(margin, y, canvasWidth-2*margin, b.height, 50, 50, shapeStyle)
+		}
+		b.textX, b.textY, b.textWidth, b.textHeight = margin+50, 10, canvasWidth-2*margin-100, b.height-20
+
+	case config.PreviewTemplatePendant:
+		radius := 150
+		b.height = 2 * radius
+		b.draw = func(canvas *svg.SVG, y int) {
+			canvas.Circle(canvasWidth/2, y+radius, radius, shapeStyle)
+		}
+		// square inscribed into the circle
+		side := int(float64(radius) * math.Sqrt2)
+		b.textX, b.textY, b.textWidth, b.textHeight = (canvasWidth-side)/2, radius-side/2, side, side
+
+	case config.PreviewTemplateBracelet:
+		b.height = 80
+		b.draw = func(canvas *svg.SVG, y int) {
+			canvas.Roundrect(margin, y, canvasWidth-2*margin, b.height, 12, 12, shapeStyle)
+		}
+		b.textX, b.textY, b.textWidth, b.textHeight = margin+20, 8, canvasWidth-2*margin-40, b.height-16
+
+	default:
+		b.height = 160
+		b.draw = func(canvas *svg.SVG, y int) {
+			canvas.Roundrect(margin+60, y, canvasWidth-2*margin-120, b.height, 12, 12, shapeStyle)
+		}
+		b.textX, b.textY, b.textWidth, b.textHeight = margin+80, 10, canvasWidth-2*margin-160, b.height-20
+	}
+
+	return b
+}
+
+func edgeBlock(label, value string) block {
+	b := block{label: label, lines: splitLines(value), height: edgeHeight}
+	b.draw = func(canvas *svg.SVG, y int) {
+		canvas.Rect(margin, y, canvasWidth-2*margin, b.height, shapeStyle)
+	}
+	b.textX, b.textY, b.textWidth, b.textHeight = margin+10, 4, canvasWidth-2*margin-20, b.height-8
+	return b
+}
+
+// drawLines centers lines in a box with the largest font which fits all of them
+func drawLines(canvas *svg.SVG, lines []string, x, y, width, height int) {
+	if len(lines) == 0 {
+		canvas.Text(x+width/2, y+height/2+5, emptyText, emptyStyle)
+		return
+	}
+
+	longest := 1
+	for _, line := range lines {
+		if length := utf8.RuneCountInString(line); length > longest {
+			longest = length
+		}
+	}
+
+	fontSize := maxFontSize
+	if byWidth := int(float64(width) / (float64(longest) * charWidth)); byWidth < fontSize {
+		fontSize = byWidth
+	}
+	if byHeight := int(float64(height) / (float64(len(lines)) * lineHeight)); byHeight < fontSize {
+		fontSize = byHeight
+	}
+	if fontSize < minFontSize {
+		fontSize = minFontSize
+	}
+
+	step := int(float64(fontSize) * lineHeight)
+	top := y + (height-step*len(lines))/2
+
+	for i, line := range lines {
+		// baseline of a line is about 0.8 of font size below its top
+		baseline := top + i*step + (step-fontSize)/2 + int(float64(fontSize)*0.8)
+		canvas.Text(x+width/2, baseline, line, fmt.Sprintf(textStyle, fontSize), "xml:space=\"preserve\"")
+	}
+}
+
+// splitLines splits an inscription into lines as it is engraved, keeping spaces within lines
+func splitLines(value string) []string {
+	var lines []string
+	for _, line := range strings.FieldsFunc(value, func(r rune) bool { return r == '\n' || r == '\r' }) {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,90 @@
+package reportshandler
+
+import (
+	"fmt"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/fieldparser"
+)
+
+type DeliveryBreakdown struct {
+	Date    string
+	Total   int
+	Methods map[string]int
+}
+
+type PickupSlot struct {
+	RowNumber    int
+	FullName     string
+	Phone        string
+	PickupNumber string
+	TimeFrom     string
+	TimeTo       string
+	TimeFromRaw  string
+	TimeToRaw    string
+	OrderLink    string
+}
+
+// GetDeliveryBreakdown returns number of orders per delivery method for every batch within
+// optional inclusive date range
+func (handler *ReportsHandler) GetDeliveryBreakdown(
+	from, to, status string,
+) ([]DeliveryBreakdown, error) {
+
+	counts, err := handler.storage.GetDeliveryCounts(from, to, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch delivery counts: %w", err)
+	}
+
+	breakdownsByDate := make(map[string]*DeliveryBreakdown)
+	var dates []string
+
+	for _, count := range counts {
+		if !fieldparser.IsBatchDate(count.Date) {
+			continue
+		}
+
+		breakdown, exists := breakdownsByDate[count.Date]
+		if !exists {
+			breakdown = &DeliveryBreakdown{Date: count.Date, Methods: make(map[string]int)}
+			breakdownsByDate[count.Date] = breakdown
+			dates = append(dates, count.Date)
+		}
+
+		breakdown.Methods[count.DeliveryMethod] += count.Count
+		breakdown.Total += count.Count
+	}
+
+	report := make([]DeliveryBreakdown, 0, len(dates))
+	for _, date := range dates {
+		report = append(report, *breakdownsByDate[date])
+	}
+
+	return report, nil
+}
+
+// GetPickupSchedule returns pickup orders of a batch ordered by pickup time window, orders
+// without a recognized time go last
+func (handler *ReportsHandler) GetPickupSchedule(date, status string) ([]PickupSlot, error) {
+	orders, err := handler.storage.GetOrdersByDeliveryMethod(date, config.DeliveryMethodPickup, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pickup orders: %w", err)
+	}
+
+	schedule := make([]PickupSlot, 0, len(orders))
+	for _, order := range orders {
+		schedule = append(schedule, PickupSlot{
+			RowNumber:    order.FirstRow,
+			FullName:     order.FullName,
+			Phone:        order.Phone,
+			PickupNumber: order.PickupNumber,
+			TimeFrom:     order.TimeFromParsed,
+			TimeTo:       order.TimeToParsed,
+			TimeFromRaw:  order.TimeFrom,
+			TimeToRaw:    order.TimeTo,
+			OrderLink:    order.OrderLink,
+		})
+	}
+
+	return schedule, nil
+}
@@ -0,0 +1,34 @@
+package reportshandler
+
+import (
+	"fmt"
+
+	"github.com/crush-on-anechka/ktn_stats/db"
+	"github.com/crush-on-anechka/ktn_stats/fieldparser"
+)
+
+type ReportsHandler struct {
+	storage *db.SqliteDB
+}
+
+func New(storage *db.SqliteDB) *ReportsHandler {
+	return &ReportsHandler{storage: storage}
+}
+
+// batchDatesInRange returns stored batch dates within optional inclusive range
+func (handler *ReportsHandler) batchDatesInRange(from, to string) ([]string, error) {
+	dates, err := handler.storage.GetDates()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch dates from db: %w", err)
+	}
+
+	var filtered []string
+	for _, date := range dates {
+		if !fieldparser.IsBatchDate(date) || (from != "" && date < from) || (to != "" && date > to) {
+			continue
+		}
+		filtered = append(filtered, date)
+	}
+
+	return filtered, nil
+}
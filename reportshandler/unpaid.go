@@ -0,0 +1,116 @@
+package reportshandler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/fieldparser"
+)
+
+type UnpaidOrder struct {
+	RowNumber     int
+	FullName      string
+	CustomerLink  string
+	Payment       string
+	PaymentStatus string
+	SumKopecks    int
+	PaidKopecks   int
+	OrderLink     string
+}
+
+type UnpaidBatch struct {
+	Date   string
+	Orders []UnpaidOrder
+}
+
+// GetUnpaidReport returns unpaid and partially paid orders grouped by batch date. If from and
+// to are empty, config.UnpaidReportBatches most recent batches are used
+func (handler *ReportsHandler) GetUnpaidReport(from, to, status string) ([]UnpaidBatch, error) {
+	var dates []string
+	var err error
+
+	if from == "" && to == "" {
+		dates, err = handler.storage.GetRecentBatchDates(config.UnpaidReportBatches)
+	} else {
+		dates, err = handler.batchDatesInRange(from, to)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch batch dates: %w", err)
+	}
+
+	orders, err := handler.storage.GetUnpaidOrders(dates, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch unpaid orders: %w", err)
+	}
+
+	batchesByDate := make(map[string]*UnpaidBatch)
+	report := []UnpaidBatch{}
+
+	for _, order := range orders {
+		batch, exists := batchesByDate[order.Date]
+		if !exists {
+			batch = &UnpaidBatch{Date: order.Date}
+			batchesByDate[order.Date] = batch
+		}
+
+		batch.Orders = append(batch.Orders, UnpaidOrder{
+			RowNumber:     order.FirstRow,
+			FullName:      order.FullName,
+			CustomerLink:  order.CustomerLink,
+			Payment:       order.Payment,
+			PaymentStatus: order.PaymentStatus,
+			SumKopecks:    order.SumKopecks,
+			PaidKopecks:   order.PaidKopecks,
+			OrderLink:     order.OrderLink,
+		})
+	}
+
+	for _, batch := range batchesByDate {
+		report = append(report, *batch)
+	}
+
+	sort.Slice(report, func(i, j int) bool { return report[i].Date > report[j].Date })
+
+	return report, nil
+}
+
+// FormatUnpaidReport renders unpaid orders report as a Telegram message listing at most
+// config.UnpaidReportOrders orders of each batch
+func FormatUnpaidReport(report []UnpaidBatch) string {
+	if len(report) == 0 {
+		return ""
+	}
+
+	var builder strings.Builder
+	builder.WriteString("Unpaid orders:")
+
+	for _, batch := range report {
+		builder.WriteString(fmt.Sprintf("\n\n%s (%d)", batch.Date, len(batch.Orders)))
+
+		for i, order := range batch.Orders {
+			if i == config.UnpaidReportOrders {
+				builder.WriteString(fmt.Sprintf("\n- and %d more, see /reports/unpaid",
+					len(batch.Orders)-config.UnpaidReportOrders))
+				break
+			}
+
+			customer := order.FullName
+			if customer == "" {
+				customer = order.CustomerLink
+			}
+
+			builder.WriteString(fmt.Sprintf("\n- row %d, %s: %s", order.RowNumber, customer, order.PaymentStatus))
+			if order.PaymentStatus == fieldparser.PaymentStatusPartial {
+				builder.WriteString(fmt.Sprintf(" %s of %s",
+					fieldparser.FormatKopecks(order.PaidKopecks), fieldparser.FormatKopecks(order.SumKopecks)))
+			} else if order.SumKopecks > 0 {
+				builder.WriteString(" " + fieldparser.FormatKopecks(order.SumKopecks))
+			}
+			builder.WriteString("\n  " + order.OrderLink)
+		}
+	}
+
+	return builder.String()
+}
@@ -3,7 +3,6 @@ package sheetsclient
 import (
 	"context"
 	"fmt"
-	"log"
 	"regexp"
 	"strconv"
 	"strings"
@@ -60,10 +59,92 @@ func (client *SheetsClient) GetSpreadsheetByYear(year string) (*sheets.Spreadshe
 	return nil, config.ErrNoRecordFound
 }
 
+// RowDetails holds cell data which is not returned with values: background colour of a row
+// ("#rrggbb", empty for rows without fill, the colour of its first filled cell), hyperlink
+// targets and notes of cells by column index
+type RowDetails struct {
+	Color      string
+	Hyperlinks map[int]string `json:",omitempty"`
+	Notes      map[int]string `json:",omitempty"`
+}
+
+// GetRowDetails returns RowDetails of every row of a range
+func (client *SheetsClient) GetRowDetails(spreadsheetID, readRange string) ([]RowDetails, error) {
+	spreadsheet, err := client.Service.Spreadsheets.Get(spreadsheetID).
+		Ranges(readRange).
+		IncludeGridData(true).
+		Fields("sheets(data(rowData(values(effectiveFormat(backgroundColor),hyperlink,note," +
+			"textFormatRuns(format(link(uri)))))))").
+		Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get grid data: %w", err)
+	}
+
+	var rows []RowDetails
+
+	for _, sheet := range spreadsheet.Sheets {
+		for _, data := range sheet.Data {
+			for _, row := range data.RowData {
+				rows = append(rows, rowDetails(row))
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+func rowDetails(row *sheets.RowData) RowDetails {
+	var details RowDetails
+
+	for colIdx, cell := range row.Values {
+		if details.Color == "" && cell.EffectiveFormat != nil && cell.EffectiveFormat.BackgroundColor != nil {
+			color := cell.EffectiveFormat.BackgroundColor
+			hex := fmt.Sprintf("#%02x%02x%02x",
+				int(color.Red*255+0.5), int(color.Green*255+0.5), int(color.Blue*255+0.5))
+
+			if hex != "#ffffff" {
+				details.Color = hex
+			}
+		}
+
+		if link := cellHyperlink(cell); link != "" {
+			if details.Hyperlinks == nil {
+				details.Hyperlinks = make(map[int]string)
+			}
+			details.Hyperlinks[colIdx] = link
+		}
+
+		if note := strings.TrimSpace(cell.Note); note != "" {
+			if details.Notes == nil {
+				details.Notes = make(map[int]string)
+			}
+			details.Notes[colIdx] = note
+		}
+	}
+
+	return details
+}
+
+// cellHyperlink returns link of a cell, rich text cells keep links in text format runs
+func cellHyperlink(cell *sheets.CellData) string {
+	if cell.Hyperlink != "" {
+		return cell.Hyperlink
+	}
+
+	for _, run := range cell.TextFormatRuns {
+		if run.Format != nil && run.Format.Link != nil && run.Format.Link.Uri != "" {
+			return run.Format.Link.Uri
+		}
+	}
+
+	return ""
+}
+
 // getFieldnamesFromSpreadsheet parses all existing column (field) names from every sheet
-// in a specified spreadsheet
+// in a specified spreadsheet, headerFieldnames extracts them from sheet values
 func (client *SheetsClient) GetFieldnamesFromSpreadsheet(
-	spreadsheet *sheets.Spreadsheet) (map[string]bool, error) {
+	spreadsheet *sheets.Spreadsheet, headerFieldnames func([][]interface{}) []string,
+) (map[string]bool, error) {
 	fieldnames := make(map[string]bool)
 
 	for _, sheet := range spreadsheet.Sheets {
@@ -81,19 +162,10 @@ func (client *SheetsClient) GetFieldnamesFromSpreadsheet(
 			return nil, fmt.Errorf("failed to retrieve data from sheet %s: %w", sheetName, err)
 		}
 
-		for _, row := range resp.Values {
-			for _, cell := range row {
-				cellStr, ok := cell.(string)
-				if !ok {
-					log.Println("Type assertion failed. The interface does not contain a string.")
-					continue
-				}
-
-				if !IsNumeric(cellStr) {
-					fieldnames[cellStr] = true
-				}
+		for _, fieldname := range headerFieldnames(resp.Values) {
+			if fieldname != "" && !IsNumeric(fieldname) {
+				fieldnames[fieldname] = true
 			}
-			break
 		}
 	}
 	return fieldnames, nil
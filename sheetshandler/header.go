@@ -0,0 +1,155 @@
+package sheetshandler
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/db"
+)
+
+// Header describes the header of a sheet: index of its first row, number of rows it takes
+// (two-row headers have group titles above column names) and canonical field name of every
+// column. Columns which are not recognized keep their text
+type Header struct {
+	Row              int
+	Rows             int
+	Fieldnames       []string
+	LinkColumnExists bool
+	Matched          int
+	Unknown          []string
+}
+
+// DataStart returns index of the first row after the header
+func (header Header) DataStart() int {
+	return header.Row + header.Rows
+}
+
+// DetectHeader scores first config.HeaderSearchRows rows (and every pair of adjacent rows
+// joined as a two-row header) by the number of cells matching known field names and aliases,
+// and returns the best one. Row 0 is used if nothing matches
+func DetectHeader(values [][]interface{}) Header {
+	knownFieldnames := knownHeaderFieldnames()
+	best := buildHeader(values, 0, 1, knownFieldnames)
+
+	for rowIdx := 0; rowIdx < config.HeaderSearchRows && rowIdx < len(values); rowIdx++ {
+		single := buildHeader(values, rowIdx, 1, knownFieldnames)
+		if single.Matched > best.Matched {
+			best = single
+		}
+
+		if rowIdx+1 >= len(values) {
+			continue
+		}
+
+		// a two-row header must match more columns than its second row alone
+		joined := buildHeader(values, rowIdx, 2, knownFieldnames)
+		next := buildHeader(values, rowIdx+1, 1, knownFieldnames)
+		if joined.Matched > best.Matched && joined.Matched > next.Matched {
+			best = joined
+		}
+	}
+
+	return best
+}
+
+// columnFieldname returns field name of a column, the first column is treated as "Ссылка" in
+// sheets without such header
+func (header Header) columnFieldname(colIdx int) string {
+	if !header.LinkColumnExists && colIdx == 0 {
+		return "Ссылка"
+	}
+	if colIdx < len(header.Fieldnames) {
+		return header.Fieldnames[colIdx]
+	}
+	return ""
+}
+
+func buildHeader(values [][]interface{}, rowIdx, rows int, knownFieldnames map[string]string) Header {
+	header := Header{Row: rowIdx, Rows: rows}
+	if rowIdx >= len(values) {
+		return header
+	}
+
+	top := values[rowIdx]
+	var bottom []interface{}
+	if rows == 2 {
+		bottom = values[rowIdx+1]
+	}
+
+	columns := len(top)
+	if len(bottom) > columns {
+		columns = len(bottom)
+	}
+
+	groupTitle := ""
+
+	for colIdx := 0; colIdx < columns; colIdx++ {
+		topCell := strings.TrimSpace(rowCell(top, colIdx))
+		bottomCell := strings.TrimSpace(rowCell(bottom, colIdx))
+
+		// a group title is written in the first column of a horizontally merged cell
+		if topCell != "" {
+			groupTitle = topCell
+		}
+
+		candidates := []string{topCell}
+		if rows == 2 {
+			candidates = []string{bottomCell, topCell, groupTitle + " " + bottomCell}
+		}
+
+		fieldname := ""
+		for _, candidate := range candidates {
+			if known, exists := knownFieldnames[strings.ToLower(candidate)]; exists {
+				fieldname = known
+				break
+			}
+		}
+
+		if fieldname != "" {
+			header.Matched++
+		} else {
+			fieldname = topCell
+			if bottomCell != "" {
+				fieldname = bottomCell
+			}
+			if fieldname != "" {
+				header.Unknown = append(header.Unknown, fieldname)
+			}
+		}
+
+		if fieldname == "Ссылка" {
+			header.LinkColumnExists = true
+		}
+		header.Fieldnames = append(header.Fieldnames, fieldname)
+	}
+
+	return header
+}
+
+// knownHeaderFieldnames maps lowercase field names of db.Data and aliases from settings to
+// field names used in db.Data tags
+func knownHeaderFieldnames() map[string]string {
+	knownFieldnames := make(map[string]string)
+
+	t := reflect.TypeOf(db.Data{})
+	for i := 0; i < t.NumField(); i++ {
+		if tag := t.Field(i).Tag.Get("fieldname"); tag != "" {
+			knownFieldnames[strings.ToLower(tag)] = tag
+		}
+	}
+
+	for alias, fieldname := range config.AppSettings.HeaderAliases {
+		knownFieldnames[strings.ToLower(alias)] = fieldname
+	}
+
+	return knownFieldnames
+}
+
+func rowCell(row []interface{}, colIdx int) string {
+	if colIdx >= len(row) {
+		return ""
+	}
+	value, _ := row[colIdx].(string)
+	return value
+}
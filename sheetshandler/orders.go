@@ -0,0 +1,84 @@
+package sheetshandler
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/crush-on-anechka/ktn_stats/db"
+	"github.com/crush-on-anechka/ktn_stats/fieldparser"
+)
+
+// AssignOrders groups rows of a single sheet (sorted by row number) into orders: a row which
+// is not merged with the one above starts a new order. OrderID of every row is set and the
+// orders are returned
+func AssignOrders(items []*db.Data) []*db.Order {
+	var orders []*db.Order
+	var current *db.Order
+
+	for _, item := range items {
+		if current == nil || !item.IsMerged {
+			current = newOrder(item)
+			orders = append(orders, current)
+		}
+
+		item.OrderID = current.ID
+		addOrderItem(current, item)
+	}
+
+	for _, order := range orders {
+		order.PaymentStatus, order.PaidKopecks = fieldparser.ParsePayment(order.Payment, order.SumKopecks)
+	}
+
+	return orders
+}
+
+func newOrder(first *db.Data) *db.Order {
+	return &db.Order{
+		ID:              fmt.Sprintf("%s-%d", first.Date, first.RowNumber),
+		Date:            first.Date,
+		FirstRow:        first.RowNumber,
+		CustomerID:      first.CustomerID,
+		FullName:        first.FullName,
+		Phone:           first.Phone,
+		PhoneNormalized: first.PhoneNormalized,
+		CustomerLink:    first.CustomerLink,
+		Socials:         first.Socials,
+		Email:           first.Email,
+		DeliveryAddress: first.DeliveryAddress,
+		OrderLink:       first.OrderLink,
+		Status:          first.Status,
+	}
+}
+
+// addOrderItem adds item sums to the order and fills delivery and payment fields which are
+// often typed in any row of a merged group. Sums copied from a merged cell are counted once
+func addOrderItem(order *db.Order, item *db.Data) {
+	order.ItemsCount++
+
+	mergedFields := strings.Split(item.MergedFields, ",")
+	if !slices.Contains(mergedFields, "Сумма") {
+		order.SumKopecks += item.SumKopecks
+	}
+	if !slices.Contains(mergedFields, "Цена доставки") {
+		order.DeliveryCostKopecks += item.DeliveryCostKopecks
+	}
+
+	if order.Email == "" {
+		order.Email = item.Email
+	}
+	if order.DeliveryType == "" && item.DeliveryType != "" {
+		order.DeliveryType = item.DeliveryType
+		order.DeliveryMethod = item.DeliveryMethod
+	}
+	if order.PickupNumber == "" {
+		order.PickupNumber = item.PickupNumber
+	}
+	if order.TimeFrom == "" && order.TimeTo == "" && (item.TimeFrom != "" || item.TimeTo != "") {
+		order.TimeFrom, order.TimeTo = item.TimeFrom, item.TimeTo
+		order.TimeFromParsed, order.TimeToParsed = item.TimeFromParsed, item.TimeToParsed
+	}
+	if order.Payment == "" {
+		order.Payment = item.Payment
+	}
+}
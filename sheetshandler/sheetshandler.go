@@ -10,13 +10,18 @@ import (
 	"log"
 	"math"
 	"reflect"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/customershandler"
 	"github.com/crush-on-anechka/ktn_stats/db"
 	"github.com/crush-on-anechka/ktn_stats/essentialshandler"
+	"github.com/crush-on-anechka/ktn_stats/fieldparser"
+	"github.com/crush-on-anechka/ktn_stats/issueshandler"
 	"github.com/crush-on-anechka/ktn_stats/sheetsclient"
 	"google.golang.org/api/sheets/v4"
 )
@@ -25,11 +30,16 @@ type SheetsHandler struct {
 	client            *sheetsclient.SheetsClient
 	storage           *db.SqliteDB
 	essentialsHandler *essentialshandler.EssentialsHandler
+	customersHandler  *customershandler.CustomersHandler
+	issuesHandler     *issueshandler.IssuesHandler
+	storedDates       []string
 }
 
 func New(storage *db.SqliteDB,
 	requestTimeout time.Duration,
 	essentialsHandler *essentialshandler.EssentialsHandler,
+	customersHandler *customershandler.CustomersHandler,
+	issuesHandler *issueshandler.IssuesHandler,
 ) (*SheetsHandler, error) {
 
 	client, err := sheetsclient.New(requestTimeout)
@@ -41,6 +51,8 @@ func New(storage *db.SqliteDB,
 		client:            client,
 		storage:           storage,
 		essentialsHandler: essentialsHandler,
+		customersHandler:  customersHandler,
+		issuesHandler:     issuesHandler,
 	}, nil
 }
 
@@ -51,6 +63,8 @@ func (handler *SheetsHandler) StoreSpreadsheetByYear(inputYear int) error {
 		return fmt.Errorf("failed to get spreadsheet by year %s: %w", inputYearAsStr, err)
 	}
 
+	sheetsStored := 0
+
 	for _, sheet := range spreadsheet.Sheets {
 		time.Sleep(handler.client.RequestTimeout)
 
@@ -75,7 +89,15 @@ func (handler *SheetsHandler) StoreSpreadsheetByYear(inputYear int) error {
 				"failed to retrieve data from, sheet %s (%v): %w", sheetName, inputYear, err)
 		}
 
-		sheetHash, err := GenerateHash(resp.Values)
+		time.Sleep(handler.client.RequestTimeout)
+
+		rowDetails, err := handler.client.GetRowDetails(spreadsheet.SpreadsheetId, readRange)
+		if err != nil {
+			return fmt.Errorf(
+				"failed to retrieve row details from sheet %s (%v): %w", sheetName, inputYear, err)
+		}
+
+		sheetHash, err := GenerateHash(resp.Values, rowDetails)
 		if err != nil {
 			return fmt.Errorf(
 				"failed to generate hash for sheet %s (%v): %w", sheetName, inputYear, err)
@@ -111,7 +133,7 @@ func (handler *SheetsHandler) StoreSpreadsheetByYear(inputYear int) error {
 		}
 
 		if err = handler.processSheet(
-			tx, sheet, sheetHash, date, spreadsheet.SpreadsheetId, resp.Values); err != nil {
+			tx, sheet, sheetHash, date, spreadsheet.SpreadsheetId, resp.Values, rowDetails); err != nil {
 			return fmt.Errorf("failed to process sheet: %w", err)
 		}
 
@@ -123,37 +145,58 @@ func (handler *SheetsHandler) StoreSpreadsheetByYear(inputYear int) error {
 			return err
 		}
 
+		if err := handler.issuesHandler.CheckOrdersByDate(date); err != nil {
+			return fmt.Errorf("failed to check data quality for %s: %w", date, err)
+		}
+
 		log.Printf("Successsfuly stored data for %v\n", date)
+		handler.storedDates = append(handler.storedDates, date)
+		sheetsStored++
+	}
+
+	if sheetsStored > 0 {
+		if err := handler.customersHandler.ResolveCustomers(); err != nil {
+			return fmt.Errorf("failed to resolve customers: %w", err)
+		}
 	}
 
 	return nil
 }
 
+// StoredDates returns dates of all sheets which were stored by the handler
+func (handler *SheetsHandler) StoredDates() []string {
+	return handler.storedDates
+}
+
 func (handler *SheetsHandler) processSheet(
-	tx *sql.Tx, sheet *sheets.Sheet, sheetHash, date, spreadsheetId string, values [][]interface{},
+	tx *sql.Tx, sheet *sheets.Sheet, sheetHash, date, spreadsheetId string,
+	values [][]interface{}, rowDetails []sheetsclient.RowDetails,
 ) error {
 
+	previousRows, err := handler.storage.GetRowsByDateWithTx(tx, date)
+	if err != nil {
+		return fmt.Errorf("failed to fetch stored rows: %w", err)
+	}
+
 	handler.storage.DeleteDataByDateWithTx(tx, date)
 
 	handler.storage.UpdateHashWithTx(tx, date, sheetHash)
 
-	fieldnamesSlice := []string{}
-	linkColumnExists := false
+	header := DetectHeader(values)
+	applyHyperlinks(values, rowDetails, header)
 	dataToBeStored := []*db.Data{}
 
-	mergedCells := getMergedCells(sheet)
+	merges := getMerges(sheet)
 
 	for rowIdx, row := range values {
-		curRowData := processRow(rowIdx, row, &fieldnamesSlice, &linkColumnExists)
-
-		_, merged := mergedCells[rowIdx]
-		if merged && len(dataToBeStored) > 0 {
-			curRowData["Ссылка"] = dataToBeStored[len(dataToBeStored)-1].CustomerLink
-			curRowData["Соцсеть"] = dataToBeStored[len(dataToBeStored)-1].Socials
-			curRowData["ФИО"] = dataToBeStored[len(dataToBeStored)-1].FullName
-			curRowData["Адрес доставки"] = dataToBeStored[len(dataToBeStored)-1].DeliveryAddress
-			curRowData["Телефон"] = dataToBeStored[len(dataToBeStored)-1].Phone
-		} else if curRowData["Ссылка"] == "" {
+		if rowIdx < header.DataStart() {
+			continue
+		}
+
+		curRowData := processRow(row, header)
+
+		merged, mergedFields := propagateMerges(rowIdx, curRowData, merges, values, header)
+		if !merged && curRowData["Ссылка"] == "" {
 			if curRowData["Сумма"] != "" {
 				log.Printf("Link is missing in an entry with not-null sum: %v, line %v\n",
 					date, rowIdx+1)
@@ -169,10 +212,22 @@ func (handler *SheetsHandler) processSheet(
 			spreadsheetId, sheetID, sheetID, rowNumber, rowNumber)
 
 		NewDataInstance := &db.Data{
-			Date:      date,
-			RowNumber: rowNumber,
-			IsMerged:  merged,
-			OrderLink: orderLink,
+			Date:           date,
+			RowNumber:      rowNumber,
+			IsMerged:       merged,
+			OrderLink:      orderLink,
+			MergedFields:   strings.Join(mergedFields, ","),
+			StatusCaptured: true,
+		}
+
+		if rowIdx < len(rowDetails) {
+			NewDataInstance.RowColor = rowDetails[rowIdx].Color
+			NewDataInstance.Status = fieldparser.ParseRowStatus(
+				rowDetails[rowIdx].Color, config.AppSettings.StatusPalette)
+
+			if err := populateNotes(NewDataInstance, rowDetails[rowIdx].Notes, header); err != nil {
+				return fmt.Errorf("failed to store notes: date %s, rowIdx: %v: %w", date, rowIdx, err)
+			}
 		}
 
 		if err := PopulateDataStructFromMap(NewDataInstance, curRowData); err != nil {
@@ -187,22 +242,44 @@ func (handler *SheetsHandler) processSheet(
 		dataToBeStored = append(dataToBeStored, NewDataInstance)
 	}
 
+	orders := AssignOrders(dataToBeStored)
+
 	if err := handler.storage.BulkInsertDataWithTx(tx, dataToBeStored); err != nil {
 		return fmt.Errorf("failed to perform bulk insert: %w", err)
 	}
 
+	if err := handler.storage.ReplaceOrdersByDateWithTx(tx, date, orders); err != nil {
+		return fmt.Errorf("failed to store orders: %w", err)
+	}
+
+	changes := statusChanges(dataToBeStored, previousRows)
+	if err := handler.storage.InsertStatusChangesWithTx(tx, changes); err != nil {
+		return fmt.Errorf("failed to store status changes: %w", err)
+	}
+
+	stats := db.IngestionStats{
+		Date:           date,
+		HeaderRow:      header.Row + 1,
+		HeaderRows:     header.Rows,
+		MatchedColumns: header.Matched,
+		UnknownColumns: strings.Join(header.Unknown, ", "),
+		RowsStored:     len(dataToBeStored),
+		OrdersStored:   len(orders),
+	}
+
+	if err := handler.storage.ReplaceIngestionStatsWithTx(tx, stats); err != nil {
+		return fmt.Errorf("failed to store ingestion stats: %w", err)
+	}
+
 	return nil
 }
 
-func processRow(
-	rowIdx int, row []interface{}, fieldnamesSlice *[]string, linkColumnExists *bool,
-) map[string]string {
-
+func processRow(row []interface{}, header Header) map[string]string {
 	curRowData := make(map[string]string)
 	curRowData["Ссылка"] = ""
 
 	for colIdx, cell := range row {
-		if rowIdx > 0 && colIdx >= len(*fieldnamesSlice) {
+		if colIdx >= len(header.Fieldnames) {
 			break
 		}
 
@@ -213,41 +290,83 @@ func processRow(
 			continue
 		}
 
-		if rowIdx == 0 {
-			if cellStr == "Ссылка" {
-				*linkColumnExists = true
-			}
-			*fieldnamesSlice = append(*fieldnamesSlice, cellStr)
-			continue
-		}
-
-		if !*linkColumnExists && colIdx == 0 {
+		if !header.LinkColumnExists && colIdx == 0 {
 			curRowData["Ссылка"] = cellStr
-		} else if cellStr != "" {
-			curRowData[(*fieldnamesSlice)[colIdx]] = cellStr
+		} else if cellStr != "" && header.Fieldnames[colIdx] != "" {
+			curRowData[header.Fieldnames[colIdx]] = cellStr
 		}
 	}
 
 	return curRowData
 }
 
-func getMergedCells(sheet *sheets.Sheet) map[int]bool {
-	mergedCells := make(map[int]bool)
+// getMerges returns merged ranges of a sheet which span more than one row
+func getMerges(sheet *sheets.Sheet) []*sheets.GridRange {
+	var merges []*sheets.GridRange
 
 	for _, mergeRange := range sheet.Merges {
-		if mergeRange.StartColumnIndex > 1 {
+		if mergeRange.EndRowIndex-mergeRange.StartRowIndex > 1 {
+			merges = append(merges, mergeRange)
+		}
+	}
+
+	return merges
+}
+
+// propagateMerges copies values of merged cells (Sheets API returns a value only for the top
+// left cell of a merge) into every row the merge spans. A row is merged with the rows above,
+// ie is an item of the same order, if a merge over one of config.OrderGroupingFields started
+// above it. Names of fields which values were copied are returned
+func propagateMerges(
+	rowIdx int, curRowData map[string]string, merges []*sheets.GridRange,
+	values [][]interface{}, header Header,
+) (bool, []string) {
+
+	merged := false
+	var mergedFields []string
+
+	for _, mergeRange := range merges {
+		startRow, startCol := int(mergeRange.StartRowIndex), int(mergeRange.StartColumnIndex)
+		if rowIdx <= startRow || rowIdx >= int(mergeRange.EndRowIndex) {
 			continue
 		}
-		for row := mergeRange.StartRowIndex + 1; row < mergeRange.EndRowIndex; row++ {
-			mergedCells[int(row)] = true
+
+		for colIdx := startCol; colIdx < int(mergeRange.EndColumnIndex); colIdx++ {
+			if isOrderGroupingField(header.columnFieldname(colIdx)) {
+				merged = true
+			}
+		}
+
+		// a merge spanning several columns shows the value of its first column only
+		fieldname := header.columnFieldname(startCol)
+		if value := cellValue(values, startRow, startCol); fieldname != "" && value != "" {
+			curRowData[fieldname] = value
+			mergedFields = append(mergedFields, fieldname)
 		}
 	}
 
-	return mergedCells
+	return merged, mergedFields
 }
 
-func GenerateHash(data [][]interface{}) (string, error) {
-	jsonData, err := json.Marshal(data)
+func isOrderGroupingField(fieldname string) bool {
+	return slices.Contains(config.AppSettings.OrderGroupingFields, fieldname)
+}
+
+func cellValue(values [][]interface{}, rowIdx, colIdx int) string {
+	if rowIdx >= len(values) || colIdx >= len(values[rowIdx]) {
+		return ""
+	}
+	value, _ := values[rowIdx][colIdx].(string)
+	return value
+}
+
+// GenerateHash hashes sheet values together with row colours, hyperlinks and notes, so that
+// changing any of them makes the sheet stored again
+func GenerateHash(data [][]interface{}, rowDetails []sheetsclient.RowDetails) (string, error) {
+	jsonData, err := json.Marshal(struct {
+		Values     [][]interface{}
+		RowDetails []sheetsclient.RowDetails
+	}{data, rowDetails})
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal sheet data: %w", err)
 	}
@@ -259,6 +378,104 @@ func GenerateHash(data [][]interface{}) (string, error) {
 	return hashAsString, nil
 }
 
+// applyHyperlinks replaces display text of link column cells (often just a customer name) with
+// the URL of their hyperlink, so that merges and parsers see the real link
+func applyHyperlinks(values [][]interface{}, rowDetails []sheetsclient.RowDetails, header Header) {
+	for rowIdx := header.DataStart(); rowIdx < len(values) && rowIdx < len(rowDetails); rowIdx++ {
+		for colIdx, link := range rowDetails[rowIdx].Hyperlinks {
+			if header.columnFieldname(colIdx) != "Ссылка" {
+				continue
+			}
+			for len(values[rowIdx]) <= colIdx {
+				values[rowIdx] = append(values[rowIdx], "")
+			}
+			values[rowIdx][colIdx] = link
+		}
+	}
+}
+
+// populateNotes stores cell notes of a row as JSON object of field names to notes, notes of
+// unnamed columns are stored by column number. NotesSearch holds uppercase notes text
+func populateNotes(data *db.Data, notes map[int]string, header Header) error {
+	if len(notes) == 0 {
+		return nil
+	}
+
+	notesByField := make(map[string]string)
+	var notesText []string
+
+	for colIdx, note := range notes {
+		fieldname := header.columnFieldname(colIdx)
+		if fieldname == "" {
+			fieldname = fmt.Sprintf("column %d", colIdx+1)
+		}
+		notesByField[fieldname] = note
+		notesText = append(notesText, note)
+	}
+
+	notesJSON, err := json.Marshal(notesByField)
+	if err != nil {
+		return err
+	}
+
+	sort.Strings(notesText)
+	data.CellNotes = string(notesJSON)
+	data.NotesSearch = strings.ToUpper(strings.Join(notesText, " "))
+
+	return nil
+}
+
+// statusChanges compares statuses of rows being stored with previously stored ones. Rows are
+// matched by content rather than row number, so rows inserted or deleted above don't change
+// statuses of the rows below. New rows are recorded only if they have a status. Sheets stored
+// before statuses were captured get their statuses without history
+func statusChanges(items []*db.Data, previousRows []db.Data) []db.StatusChange {
+	previousByKey := make(map[string][]db.Data)
+	for _, row := range previousRows {
+		if !row.StatusCaptured {
+			return nil
+		}
+		previousByKey[statusKey(&row)] = append(previousByKey[statusKey(&row)], row)
+	}
+
+	var changes []db.StatusChange
+	changedAt := time.Now().Format(time.DateTime)
+
+	for _, item := range items {
+		var previousStatus string
+		exists := false
+
+		// identical rows are matched in order of their row numbers
+		if matches := previousByKey[statusKey(item)]; len(matches) > 0 {
+			previousStatus, exists = matches[0].Status, true
+			previousByKey[statusKey(item)] = matches[1:]
+		}
+
+		if previousStatus == item.Status || (!exists && item.Status == "") {
+			continue
+		}
+
+		changes = append(changes, db.StatusChange{
+			Date:           item.Date,
+			RowNumber:      item.RowNumber,
+			OrderID:        item.OrderID,
+			PreviousStatus: previousStatus,
+			Status:         item.Status,
+			ChangedAt:      changedAt,
+		})
+	}
+
+	return changes
+}
+
+// statusKey identifies a row within its sheet by customer and item fields
+func statusKey(item *db.Data) string {
+	return strings.Join([]string{
+		item.CustomerLink, item.FullName, item.Phone, item.Type, item.Subtype, item.Inscription,
+		item.Ring, item.Pendant, item.InscriptionBracelet, item.EdgeUpper, item.EdgeLower,
+	}, "\x1f")
+}
+
 func SerializeDate(sheetName, year string) string {
 	parts := strings.Split(sheetName, ".")
 	day := fmt.Sprintf("%02s", parts[0])
@@ -304,9 +521,64 @@ func PopulateDataStructFromMap(data *db.Data, values map[string]string) error {
 			return fmt.Errorf("unsupported field type %s for tag %s", fieldValue.Kind(), tag)
 		}
 	}
+
+	phoneNormalized, ok := fieldparser.NormalizePhone(data.Phone)
+	data.PhoneNormalized = phoneNormalized
+	data.PhoneInvalid = !ok
+
+	data.SocialNetwork, data.SocialHandle = fieldparser.ParseSocialLink(data.CustomerLink, data.Socials)
+
+	populateAddressFields(data)
+
+	populateMoneyFields(data, values)
+
+	data.DeliveryMethod = fieldparser.ParseDeliveryMethod(data.DeliveryType, config.AppSettings.DeliveryMethods)
+	data.TimeFromParsed = fieldparser.ParseTimeOfDay(data.TimeFrom)
+	data.TimeToParsed = fieldparser.ParseTimeOfDay(data.TimeTo)
+
 	return nil
 }
 
+// populateMoneyFields parses "Сумма" and "Цена доставки" into kopecks keeping the raw sum and
+// parse statuses, Sum holds the same value rounded to rubles. "Оплата" is classified into
+// payment status afterwards since it depends on the sum
+func populateMoneyFields(data *db.Data, values map[string]string) {
+	data.SumRaw = values["Сумма"]
+	data.SumKopecks, data.SumStatus = fieldparser.ParseMoney(data.SumRaw)
+	data.Sum = int(math.Round(float64(data.SumKopecks) / 100))
+
+	data.DeliveryCostKopecks, data.DeliveryCostStatus = fieldparser.ParseMoney(data.DeliveryCost)
+
+	data.PaymentStatus, data.PaidKopecks = fieldparser.ParsePayment(data.Payment, data.SumKopecks)
+}
+
+// populateAddressFields parses DeliveryAddress and cross-checks post code from the address
+// with "Индекс" column
+func populateAddressFields(data *db.Data) {
+	postCode := fieldparser.NormalizePostCode(data.PostCode)
+
+	if data.DeliveryAddress == "" {
+		data.AddressPostCode = postCode
+		return
+	}
+
+	address := fieldparser.ParseAddress(data.DeliveryAddress, data.City)
+
+	data.AddressRegion = address.Region
+	data.AddressCity = address.City
+	data.AddressStreet = address.Street
+	data.AddressHouse = address.House
+	data.AddressApartment = address.Apartment
+	data.AddressConfidence = address.Confidence
+	data.AddressPostCode = address.PostCode
+
+	if address.PostCode == "" {
+		data.AddressPostCode = postCode
+	} else if postCode != "" && postCode != address.PostCode {
+		data.PostCodeMismatch = true
+	}
+}
+
 func handleSearchField(NewDataInstance *db.Data) {
 	v := reflect.ValueOf(NewDataInstance).Elem()
 	t := v.Type()
@@ -0,0 +1,59 @@
+package tasks
+
+import (
+	"fmt"
+
+	"github.com/crush-on-anechka/ktn_stats/customershandler"
+	"github.com/crush-on-anechka/ktn_stats/db"
+	"github.com/crush-on-anechka/ktn_stats/sheetshandler"
+)
+
+// BuildOrders groups already stored rows of every sheet into orders by IsMerged flag, so
+// that DBs filled by previous versions get Orders table without re-fetching sheets
+func BuildOrders() error {
+	storage, err := db.NewSqliteDB()
+	if err != nil {
+		return fmt.Errorf("failed to establish connection with database: %w", err)
+	}
+	defer storage.DB.Close()
+
+	dates, err := storage.GetDates()
+	if err != nil {
+		return fmt.Errorf("failed to fetch dates from db: %w", err)
+	}
+
+	for _, date := range dates {
+		rows, err := storage.GetOrdersByDate(date)
+		if err != nil {
+			return fmt.Errorf("failed to fetch data for date %s: %w", date, err)
+		}
+
+		items := make([]*db.Data, len(rows))
+		for i := range rows {
+			items[i] = &rows[i]
+		}
+
+		orders := sheetshandler.AssignOrders(items)
+
+		tx, err := storage.BeginTransaction()
+		if err != nil {
+			return err
+		}
+
+		if err = storage.UpdateOrderIDsWithTx(tx, items); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to store order IDs for date %s: %w", date, err)
+		}
+
+		if err = storage.ReplaceOrdersByDateWithTx(tx, date, orders); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to store orders for date %s: %w", date, err)
+		}
+
+		if err = tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+	}
+
+	return customershandler.New(storage).ResolveCustomers()
+}
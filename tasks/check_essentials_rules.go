@@ -0,0 +1,26 @@
+package tasks
+
+import (
+	"fmt"
+
+	"github.com/crush-on-anechka/ktn_stats/db"
+	"github.com/crush-on-anechka/ktn_stats/essentialshandler"
+)
+
+// CheckEssentialsRules builds a report of rows excluded from essentials by every rule
+func CheckEssentialsRules() (string, error) {
+	storage, err := db.NewSqliteDB()
+	if err != nil {
+		return "", fmt.Errorf("failed to establish connection with database: %w", err)
+	}
+	defer storage.DB.Close()
+
+	essentialsHandler := essentialshandler.New(storage)
+
+	reports, err := essentialsHandler.GetRuleReports()
+	if err != nil {
+		return "", fmt.Errorf("failed to check essentials rules: %w", err)
+	}
+
+	return essentialshandler.FormatRuleReports(reports), nil
+}
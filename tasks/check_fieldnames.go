@@ -9,6 +9,7 @@ import (
 	"github.com/crush-on-anechka/ktn_stats/config"
 	"github.com/crush-on-anechka/ktn_stats/db"
 	"github.com/crush-on-anechka/ktn_stats/sheetsclient"
+	"github.com/crush-on-anechka/ktn_stats/sheetshandler"
 )
 
 // CheckFieldnames parses fieldnames from most recent spreadsheet and checks if they all are
@@ -27,7 +28,8 @@ func CheckFieldnames() error {
 		return fmt.Errorf("failed to get spreadsheet by year %s: %w", yearAsStr, err)
 	}
 
-	fieldnamesFromSheets, err := client.GetFieldnamesFromSpreadsheet(currentSpreadsheet)
+	fieldnamesFromSheets, err := client.GetFieldnamesFromSpreadsheet(currentSpreadsheet,
+		func(values [][]interface{}) []string { return sheetshandler.DetectHeader(values).Fieldnames })
 	if err != nil {
 		return fmt.Errorf("failed to get spreadsheet fieldnames: %w", err)
 	}
@@ -0,0 +1,32 @@
+package tasks
+
+import (
+	"fmt"
+
+	"github.com/crush-on-anechka/ktn_stats/db"
+	"github.com/crush-on-anechka/ktn_stats/issueshandler"
+)
+
+// CheckIssues runs data quality rules over every stored sheet
+func CheckIssues() (string, error) {
+	storage, err := db.NewSqliteDB()
+	if err != nil {
+		return "", fmt.Errorf("failed to establish connection with database: %w", err)
+	}
+	defer storage.DB.Close()
+
+	dates, err := storage.GetDates()
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch dates from db: %w", err)
+	}
+
+	issuesHandler := issueshandler.New(storage)
+
+	for _, date := range dates {
+		if err = issuesHandler.CheckOrdersByDate(date); err != nil {
+			return "", fmt.Errorf("failed to check data quality for date %s: %w", date, err)
+		}
+	}
+
+	return buildIngestReport(issuesHandler, dates)
+}
@@ -0,0 +1,31 @@
+package tasks
+
+import (
+	"fmt"
+
+	"github.com/crush-on-anechka/ktn_stats/issueshandler"
+)
+
+// buildIngestReport summarizes problems found in freshly stored sheets. The report is sent
+// to Telegram after store tasks, empty report means there is nothing to send
+func buildIngestReport(issuesHandler *issueshandler.IssuesHandler, dates []string) (string, error) {
+	if len(dates) == 0 {
+		return "", nil
+	}
+
+	issuesSummary, err := issuesHandler.Summary(dates)
+	if err != nil {
+		return "", fmt.Errorf("failed to build issues summary: %w", err)
+	}
+
+	engravingSummary, err := issuesHandler.EngravingSummary(dates)
+	if err != nil {
+		return "", fmt.Errorf("failed to build engraving constraints summary: %w", err)
+	}
+
+	if issuesSummary == "" || engravingSummary == "" {
+		return issuesSummary + engravingSummary, nil
+	}
+
+	return issuesSummary + "\n\n" + engravingSummary, nil
+}
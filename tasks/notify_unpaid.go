@@ -0,0 +1,26 @@
+package tasks
+
+import (
+	"fmt"
+
+	"github.com/crush-on-anechka/ktn_stats/db"
+	"github.com/crush-on-anechka/ktn_stats/reportshandler"
+)
+
+// NotifyUnpaid builds a report of unpaid and partially paid orders from recent batches
+func NotifyUnpaid() (string, error) {
+	storage, err := db.NewSqliteDB()
+	if err != nil {
+		return "", fmt.Errorf("failed to establish connection with database: %w", err)
+	}
+	defer storage.DB.Close()
+
+	reportsHandler := reportshandler.New(storage)
+
+	report, err := reportsHandler.GetUnpaidReport("", "", "")
+	if err != nil {
+		return "", fmt.Errorf("failed to build unpaid orders report: %w", err)
+	}
+
+	return reportshandler.FormatUnpaidReport(report), nil
+}
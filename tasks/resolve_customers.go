@@ -0,0 +1,47 @@
+package tasks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crush-on-anechka/ktn_stats/customershandler"
+	"github.com/crush-on-anechka/ktn_stats/db"
+)
+
+// ResolveCustomers rebuilds Customers table and CustomerID column of every stored order
+func ResolveCustomers() error {
+	storage, err := db.NewSqliteDB()
+	if err != nil {
+		return fmt.Errorf("failed to establish connection with database: %w", err)
+	}
+	defer storage.DB.Close()
+
+	customersHandler := customershandler.New(storage)
+
+	return customersHandler.ResolveCustomers()
+}
+
+// OverrideCustomers stores a merge or split override for two comma separated customer keys
+// (eg "phone:+79161234567,link:vk/id123") and re-resolves customers
+func OverrideCustomers(kind, keys string) error {
+	storage, err := db.NewSqliteDB()
+	if err != nil {
+		return fmt.Errorf("failed to establish connection with database: %w", err)
+	}
+	defer storage.DB.Close()
+
+	keysSlice := strings.Split(keys, ",")
+	if len(keysSlice) != 2 {
+		return fmt.Errorf("expected two comma separated customer keys, got %q", keys)
+	}
+
+	customersHandler := customershandler.New(storage)
+
+	err = customersHandler.AddOverride(
+		kind, strings.TrimSpace(keysSlice[0]), strings.TrimSpace(keysSlice[1]))
+	if err != nil {
+		return fmt.Errorf("failed to store customers override: %w", err)
+	}
+
+	return customersHandler.ResolveCustomers()
+}
@@ -5,23 +5,28 @@ import (
 	"time"
 
 	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/customershandler"
 	"github.com/crush-on-anechka/ktn_stats/db"
 	"github.com/crush-on-anechka/ktn_stats/essentialshandler"
+	"github.com/crush-on-anechka/ktn_stats/issueshandler"
 	"github.com/crush-on-anechka/ktn_stats/sheetshandler"
 )
 
-func StoreAllSpreadsheets() error {
+func StoreAllSpreadsheets() (string, error) {
 	storage, err := db.NewSqliteDB()
 	if err != nil {
-		return fmt.Errorf("failed to establish connection with database: %w", err)
+		return "", fmt.Errorf("failed to establish connection with database: %w", err)
 	}
 	defer storage.DB.Close()
 
 	essentialsHandler := essentialshandler.New(storage)
+	customersHandler := customershandler.New(storage)
+	issuesHandler := issueshandler.New(storage)
 
-	sheetsHandler, err := sheetshandler.New(storage, config.SafeRequestTimeout, essentialsHandler)
+	sheetsHandler, err := sheetshandler.New(
+		storage, config.SafeRequestTimeout, essentialsHandler, customersHandler, issuesHandler)
 	if err != nil {
-		return fmt.Errorf("failed to create sheetshandler: %w", err)
+		return "", fmt.Errorf("failed to create sheetshandler: %w", err)
 	}
 
 	currentYear := time.Now().Year()
@@ -29,9 +34,9 @@ func StoreAllSpreadsheets() error {
 	for year := config.StartYear; year <= currentYear; year++ {
 		err := sheetsHandler.StoreSpreadsheetByYear(year)
 		if err != nil {
-			return fmt.Errorf("failed to store %v spreadsheet: %w", year, err)
+			return "", fmt.Errorf("failed to store %v spreadsheet: %w", year, err)
 		}
 	}
 
-	return nil
+	return buildIngestReport(issuesHandler, sheetsHandler.StoredDates())
 }
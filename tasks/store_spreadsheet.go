@@ -5,29 +5,38 @@ import (
 	"strconv"
 
 	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/customershandler"
 	"github.com/crush-on-anechka/ktn_stats/db"
 	"github.com/crush-on-anechka/ktn_stats/essentialshandler"
+	"github.com/crush-on-anechka/ktn_stats/issueshandler"
 	"github.com/crush-on-anechka/ktn_stats/sheetshandler"
 )
 
-func StoreSpreadsheet(year string) error {
+func StoreSpreadsheet(year string) (string, error) {
 	storage, err := db.NewSqliteDB()
 	if err != nil {
-		return fmt.Errorf("failed to establish connection with database: %w", err)
+		return "", fmt.Errorf("failed to establish connection with database: %w", err)
 	}
 	defer storage.DB.Close()
 
 	essentialsHandler := essentialshandler.New(storage)
+	customersHandler := customershandler.New(storage)
+	issuesHandler := issueshandler.New(storage)
 
-	sheetsHandler, err := sheetshandler.New(storage, config.GreedyRequestTimeout, essentialsHandler)
+	sheetsHandler, err := sheetshandler.New(
+		storage, config.GreedyRequestTimeout, essentialsHandler, customersHandler, issuesHandler)
 	if err != nil {
-		return fmt.Errorf("failed to create sheetshandler: %w", err)
+		return "", fmt.Errorf("failed to create sheetshandler: %w", err)
 	}
 
 	yearAsInt, err := strconv.Atoi(year)
 	if err != nil {
-		return fmt.Errorf("failed to parse year: %w", err)
+		return "", fmt.Errorf("failed to parse year: %w", err)
 	}
 
-	return sheetsHandler.StoreSpreadsheetByYear(yearAsInt)
+	if err := sheetsHandler.StoreSpreadsheetByYear(yearAsInt); err != nil {
+		return "", err
+	}
+
+	return buildIngestReport(issuesHandler, sheetsHandler.StoredDates())
 }
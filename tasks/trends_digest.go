@@ -0,0 +1,34 @@
+package tasks
+
+import (
+	"fmt"
+
+	"github.com/crush-on-anechka/ktn_stats/config"
+	"github.com/crush-on-anechka/ktn_stats/db"
+	"github.com/crush-on-anechka/ktn_stats/essentialshandler"
+)
+
+// TrendsDigest builds a digest of trending inscription words and phrases of recent weeks
+// compared with the same weeks a year before
+func TrendsDigest() (string, error) {
+	storage, err := db.NewSqliteDB()
+	if err != nil {
+		return "", fmt.Errorf("failed to establish connection with database: %w", err)
+	}
+	defer storage.DB.Close()
+
+	essentialsHandler := essentialshandler.New(storage)
+
+	var reports []*essentialshandler.TrendsReport
+
+	for _, kind := range []string{config.EssentialKindWord, config.EssentialKindPhrase} {
+		report, err := essentialsHandler.GetTrends(
+			"", "", kind, config.TrendsMinSupport, config.TrendsDigestLimit)
+		if err != nil {
+			return "", fmt.Errorf("failed to build %s trends: %w", kind, err)
+		}
+		reports = append(reports, report)
+	}
+
+	return essentialshandler.FormatTrendsDigest(reports...), nil
+}